@@ -17,4 +17,10 @@ var (
 	
 	// ErrScriptNotCached is returned when a script's SHA1 is in the registry but the script itself is not loaded in the Redis script cache.
 	ErrScriptNotCached = errors.New("goscriptor: script not in cache, reload required")
+	
+	// ErrClusterDB is returned when a non-zero script DB is used with a Redis Cluster client.
+	ErrClusterDB = errors.New("goscriptor: Redis Cluster only supports script DB 0")
+	
+	// ErrDefinitionInUse is returned when deleting the script definition of the Scriptor itself.
+	ErrDefinitionInUse = errors.New("goscriptor: script definition is in use")
 )
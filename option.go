@@ -1,6 +1,8 @@
 package goscriptor
 
 import (
+	"context"
+	"crypto/tls"
 	"strconv"
 
 	"github.com/yshengliao/goscriptor/redis"
@@ -13,14 +15,23 @@ type Option struct {
 	Password string
 	DB       int
 	PoolSize int
+
+	// TLSConfig enables TLS when non-nil; see redis.Options.TLSConfig.
+	TLSConfig *tls.Config
+
+	// CredentialsProvider supplies rotating credentials in place of
+	// Password; see redis.Options.CredentialsProvider.
+	CredentialsProvider func(ctx context.Context) (username, password string, err error)
 }
 
 // Create creates a new Redis client from this option.
 func (opt *Option) Create() *redis.Client {
 	return redis.NewClient(&redis.Options{
-		Addr:     opt.Host + ":" + strconv.Itoa(opt.Port),
-		Password: opt.Password,
-		DB:       opt.DB,
-		PoolSize: opt.PoolSize,
+		Addr:                opt.Host + ":" + strconv.Itoa(opt.Port),
+		Password:            opt.Password,
+		DB:                  opt.DB,
+		PoolSize:            opt.PoolSize,
+		TLSConfig:           opt.TLSConfig,
+		CredentialsProvider: opt.CredentialsProvider,
 	})
 }
@@ -1,7 +1,12 @@
 package goscriptor_test
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/yshengliao/goscriptor"
@@ -29,3 +34,53 @@ func TestOption_Create_Ping(t *testing.T) {
 		t.Fatalf("Ping failed: %v", err)
 	}
 }
+
+func TestOption_Create_TLS(t *testing.T) {
+	// Borrow httptest's certificate for 127.0.0.1.
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	ts.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: ts.TLS.Certificates})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer nc.Close()
+				rd := bufio.NewReader(nc)
+				for {
+					line, err := rd.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if strings.EqualFold(strings.TrimSpace(line), "PING") {
+						nc.Write([]byte("+PONG\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	host, port := splitAddr(t, ln.Addr().String())
+	opt := &goscriptor.Option{
+		Host:      host,
+		Port:      port,
+		PoolSize:  1,
+		TLSConfig: &tls.Config{RootCAs: roots},
+	}
+
+	client := opt.Create()
+	defer client.Close()
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping over TLS failed: %v", err)
+	}
+}
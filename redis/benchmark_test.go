@@ -35,3 +35,21 @@ func BenchmarkGet(b *testing.B) {
 		c.Get(ctx, "mybenchkey")
 	}
 }
+
+func BenchmarkPipeline100(b *testing.B) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		b.Skip("REDIS_ADDR not set")
+	}
+	c := redis.NewClient(&redis.Options{Addr: addr, PoolSize: 1})
+	defer c.Close()
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := c.Pipeline()
+		for range 100 {
+			p.Ping()
+		}
+		p.Exec(ctx)
+	}
+}
@@ -0,0 +1,295 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default circuit breaker settings.
+const (
+	defaultCircuitWindow      = 10 * time.Second
+	defaultCircuitMinRequests = 20
+	defaultCircuitFailureRate = 0.5
+	defaultCircuitCoolDown    = 5 * time.Second
+)
+
+// CircuitBreakerOptions configures the circuit breaker enabled by
+// Options.CircuitBreaker. While the breaker is open, commands and pipelines
+// fail at once with ErrCircuitOpen instead of waiting for DialTimeout or
+// ReadTimeout against an unavailable server.
+type CircuitBreakerOptions struct {
+	// Window is the period over which commands and failures are counted.
+	// Default: 10s.
+	Window time.Duration
+
+	// MinRequests is the number of commands in a window below which the
+	// breaker does not open, however many of them failed.
+	// Default: 20.
+	MinRequests int
+
+	// FailureRate is the fraction of failed commands in a window, from 0
+	// to 1, that opens the breaker.
+	// Default: 0.5.
+	FailureRate float64
+
+	// CoolDown is how long the breaker stays open before letting probe
+	// commands through (half-open).
+	// Default: 5s.
+	CoolDown time.Duration
+
+	// HalfOpenRequests is the number of probe commands let through while
+	// half-open. The breaker closes when all of them succeed and opens
+	// again on the first failure. A probe still running after CoolDown,
+	// e.g. a blocking command, gives its slot to the next command.
+	// Default: 1.
+	HalfOpenRequests int
+
+	// IsFailure reports whether a command error counts as a failure. The
+	// default counts I/O errors, timeouts, context deadlines and
+	// ErrPoolTimeout, but not error replies, which show that the server is
+	// up, nor context cancellation, ErrTxFailed or ErrCircuitOpen.
+	IsFailure func(err error) bool
+
+	// OnStateChange, if set, is called on every state transition.
+	OnStateChange func(from, to CircuitState)
+}
+
+func (o *CircuitBreakerOptions) window() time.Duration {
+	if o.Window > 0 {
+		return o.Window
+	}
+	return defaultCircuitWindow
+}
+
+func (o *CircuitBreakerOptions) minRequests() int {
+	if o.MinRequests > 0 {
+		return o.MinRequests
+	}
+	return defaultCircuitMinRequests
+}
+
+func (o *CircuitBreakerOptions) failureRate() float64 {
+	if o.FailureRate > 0 {
+		return o.FailureRate
+	}
+	return defaultCircuitFailureRate
+}
+
+func (o *CircuitBreakerOptions) coolDown() time.Duration {
+	if o.CoolDown > 0 {
+		return o.CoolDown
+	}
+	return defaultCircuitCoolDown
+}
+
+func (o *CircuitBreakerOptions) halfOpenRequests() int {
+	if o.HalfOpenRequests > 0 {
+		return o.HalfOpenRequests
+	}
+	return 1
+}
+
+func (o *CircuitBreakerOptions) isFailure(err error) bool {
+	if o.IsFailure != nil {
+		return o.IsFailure(err)
+	}
+	var rerr RedisError
+	if errors.As(err, &rerr) {
+		return false
+	}
+	switch {
+	case errors.Is(err, context.Canceled),
+		errors.Is(err, ErrTxFailed),
+		errors.Is(err, ErrCircuitOpen):
+		return false
+	}
+	return true
+}
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // commands go through
+	CircuitOpen                         // commands fail with ErrCircuitOpen
+	CircuitHalfOpen                     // a few probe commands go through
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// circuitBreaker counts failures over a fixed window and trips when their
+// rate crosses the threshold.
+type circuitBreaker struct {
+	opts *CircuitBreakerOptions
+
+	mu          sync.Mutex
+	state       CircuitState
+	openedAt    time.Time
+	probedAt    time.Time // last probe let through when half-open
+	windowStart time.Time
+	requests    int // in the current window, or probes let through when half-open
+	failures    int // in the current window, or probes succeeded when half-open
+
+	rejected atomic.Uint64
+}
+
+func newCircuitBreaker(opts *CircuitBreakerOptions) *circuitBreaker {
+	return &circuitBreaker{opts: opts, windowStart: time.Now()}
+}
+
+// allow reports whether a command may run. probe is set for the commands
+// let through while half-open.
+func (b *circuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	var changed func()
+	defer func() {
+		b.mu.Unlock()
+		if changed != nil {
+			changed()
+		}
+	}()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.opts.coolDown() {
+			b.rejected.Add(1)
+			return false, ErrCircuitOpen
+		}
+		changed = b.setState(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		now := time.Now()
+		if b.requests >= b.opts.halfOpenRequests() {
+			if now.Sub(b.probedAt) < b.opts.coolDown() {
+				b.rejected.Add(1)
+				return false, ErrCircuitOpen
+			}
+			// The probes are stuck, e.g. on a blocking command: free
+			// their slots. Their outcome still counts if they return.
+			b.requests = b.failures
+		}
+		b.requests++
+		b.probedAt = now
+		return true, nil
+	}
+	return false, nil
+}
+
+// done records the outcome of a command let through by allow.
+func (b *circuitBreaker) done(probe bool, err error) {
+	failed := err != nil && !errors.Is(err, ErrCircuitOpen) && b.opts.isFailure(err)
+
+	b.mu.Lock()
+	var changed func()
+	defer func() {
+		b.mu.Unlock()
+		if changed != nil {
+			changed()
+		}
+	}()
+
+	switch {
+	case probe && b.state == CircuitHalfOpen:
+		if failed {
+			changed = b.setState(CircuitOpen)
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			if b.requests > b.failures {
+				b.requests-- // inconclusive: let another probe through
+			}
+			return
+		}
+		b.failures++ // successful probes
+		if b.failures >= b.opts.halfOpenRequests() {
+			changed = b.setState(CircuitClosed)
+		}
+	case !probe && b.state == CircuitClosed:
+		now := time.Now()
+		if now.Sub(b.windowStart) > b.opts.window() {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.opts.minRequests() &&
+			float64(b.failures) >= b.opts.failureRate()*float64(b.requests) {
+			changed = b.setState(CircuitOpen)
+		}
+	}
+}
+
+// setState moves to state with b.mu held, resetting the counters, and
+// returns the OnStateChange call to make once b.mu is released.
+func (b *circuitBreaker) setState(state CircuitState) func() {
+	from := b.state
+	now := time.Now()
+	b.state = state
+	b.requests, b.failures = 0, 0
+	b.windowStart = now
+	if state == CircuitOpen {
+		b.openedAt = now
+	}
+	if f := b.opts.OnStateChange; f != nil {
+		return func() { f(from, state) }
+	}
+	return nil
+}
+
+func (b *circuitBreaker) currentState() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.opts.coolDown() {
+		return CircuitHalfOpen // on the next command
+	}
+	return b.state
+}
+
+// circuitHook short-circuits commands and pipelines while the breaker is
+// open. It is installed by NewClient, after the tracing hook.
+type circuitHook struct {
+	b *circuitBreaker
+}
+
+func (h circuitHook) DialHook(next DialHook) DialHook { return next }
+
+func (h circuitHook) ProcessHook(next ProcessHook) ProcessHook {
+	return func(ctx context.Context, args []any) (any, error) {
+		probe, err := h.b.allow()
+		if err != nil {
+			return nil, err
+		}
+		reply, err := next(ctx, args)
+		h.b.done(probe, err)
+		return reply, err
+	}
+}
+
+func (h circuitHook) ProcessPipelineHook(next ProcessPipelineHook) ProcessPipelineHook {
+	return func(ctx context.Context, cmds []*Cmd) error {
+		probe, err := h.b.allow()
+		if err != nil {
+			for _, cmd := range cmds {
+				cmd.err = err
+			}
+			return err
+		}
+		err = next(ctx, cmds)
+		h.b.done(probe, err)
+		return err
+	}
+}
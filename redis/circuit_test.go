@@ -0,0 +1,245 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	errDial := errors.New("refused")
+	var fail atomic.Bool
+	var dials atomic.Int32
+	var mu sync.Mutex
+	var transitions []string
+	s := nameServer(t, "a")
+	c := redis.NewClient(&redis.Options{
+		Addr: s.addr(),
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dials.Add(1)
+			if fail.Load() {
+				return nil, errDial
+			}
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+		CircuitBreaker: &redis.CircuitBreakerOptions{
+			MinRequests: 4,
+			CoolDown:    50 * time.Millisecond,
+			OnStateChange: func(from, to redis.CircuitState) {
+				mu.Lock()
+				transitions = append(transitions, fmt.Sprintf("%v>%v", from, to))
+				mu.Unlock()
+			},
+		},
+	})
+	defer c.Close()
+	ctx := context.Background()
+
+	// Error replies do not count: the server is up.
+	for range 5 {
+		if _, err := c.Do(ctx, "BOGUS"); err != nil {
+			t.Fatalf("BOGUS: %v", err)
+		}
+	}
+
+	// With 5 successes in the window, the failure rate reaches 50% at the
+	// 5th failure.
+	fail.Store(true)
+	s.dropConns()
+	for i := range 5 {
+		if _, err := c.Get(ctx, "k"); err == nil {
+			t.Fatalf("expected GET %d to fail", i)
+		}
+		if st := c.Stats(); i < 4 && st.Circuit != redis.CircuitClosed {
+			t.Fatalf("expected closed after %d failures, got %v", i+1, st.Circuit)
+		}
+	}
+	n := dials.Load()
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, redis.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if _, err := c.Eval(ctx, "return 1", nil); !errors.Is(err, redis.ErrCircuitOpen) {
+		t.Fatalf("Eval: expected ErrCircuitOpen, got %v", err)
+	}
+	if _, err := c.EvalSha(ctx, "abc", nil); !errors.Is(err, redis.ErrCircuitOpen) {
+		t.Fatalf("EvalSha: expected ErrCircuitOpen, got %v", err)
+	}
+	if _, err := c.Pipelined(ctx, func(p *redis.Pipeline) error {
+		p.Get("k")
+		return nil
+	}); !errors.Is(err, redis.ErrCircuitOpen) {
+		t.Fatalf("Pipelined: expected ErrCircuitOpen, got %v", err)
+	}
+	if dials.Load() != n {
+		t.Fatal("expected no dial while the breaker is open")
+	}
+	st := c.Stats()
+	if st.Circuit != redis.CircuitOpen || st.CircuitRejects != 4 {
+		t.Fatalf("unexpected circuit stats %v, %d", st.Circuit, st.CircuitRejects)
+	}
+	var b strings.Builder
+	st.WritePrometheus(&b, "")
+	if !strings.Contains(b.String(), "redis_circuit_state 1\n") || !strings.Contains(b.String(), "redis_circuit_rejects_total 4\n") {
+		t.Fatalf("missing circuit metrics in:\n%s", b.String())
+	}
+
+	// After the cool-down a failed probe opens it again...
+	time.Sleep(60 * time.Millisecond)
+	if st := c.Stats(); st.Circuit != redis.CircuitHalfOpen {
+		t.Fatalf("expected half-open, got %v", st.Circuit)
+	}
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, errDial) {
+		t.Fatalf("expected the probe to fail with %v, got %v", errDial, err)
+	}
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, redis.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	// ...and a successful one closes it.
+	fail.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	if got := whoami(t, c, "GET", "k"); got != "a" {
+		t.Fatalf("expected a, got %q", got)
+	}
+	if st := c.Stats(); st.Circuit != redis.CircuitClosed {
+		t.Fatalf("expected closed, got %v", st.Circuit)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"closed>open", "open>half-open", "half-open>open",
+		"open>half-open", "half-open>closed",
+	}
+	if !slices.Equal(transitions, want) {
+		t.Fatalf("expected transitions %q, got %q", want, transitions)
+	}
+}
+
+func TestClient_CircuitBreakerIsFailure(t *testing.T) {
+	s := newFakeServer(t, func([]string) string {
+		return "-MASTERDOWN Link with MASTER is down\r\n"
+	})
+	c := redis.NewClient(&redis.Options{
+		Addr:       s.addr(),
+		MaxRetries: -1,
+		CircuitBreaker: &redis.CircuitBreakerOptions{
+			MinRequests: 2,
+			IsFailure: func(err error) bool {
+				return errors.Is(err, redis.ErrMasterDown)
+			},
+		},
+	})
+	defer c.Close()
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := c.Do(ctx, "SET", "k", "v"); !errors.Is(err, redis.ErrMasterDown) {
+			t.Fatalf("expected ErrMasterDown, got %v", err)
+		}
+	}
+	if _, err := c.Do(ctx, "SET", "k", "v"); !errors.Is(err, redis.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestClient_CircuitBreakerTxFailed(t *testing.T) {
+	s := newFakeServer(t, func(args []string) string {
+		switch args[0] {
+		case "MULTI":
+			return "+OK\r\n"
+		case "EXEC":
+			return "*-1\r\n"
+		}
+		return "+QUEUED\r\n"
+	})
+	c := redis.NewClient(&redis.Options{
+		Addr:           s.addr(),
+		CircuitBreaker: &redis.CircuitBreakerOptions{MinRequests: 2},
+	})
+	defer c.Close()
+	ctx := context.Background()
+
+	// An aborted transaction shows that the server is up.
+	for range 3 {
+		if _, err := c.TxPipelined(ctx, func(p *redis.Pipeline) error {
+			p.Set("k", "v", 0)
+			return nil
+		}); !errors.Is(err, redis.ErrTxFailed) {
+			t.Fatalf("expected ErrTxFailed, got %v", err)
+		}
+	}
+	if st := c.Stats(); st.Circuit != redis.CircuitClosed {
+		t.Fatalf("expected closed, got %v", st.Circuit)
+	}
+}
+
+func TestClient_CircuitBreakerStuckProbe(t *testing.T) {
+	release := make(chan struct{})
+	s := newFakeServer(t, func(args []string) string {
+		switch args[0] {
+		case "FAIL":
+			return "-MASTERDOWN Link with MASTER is down\r\n"
+		case "SLOW":
+			<-release
+		}
+		return bulk("a")
+	})
+	c := redis.NewClient(&redis.Options{
+		Addr:        s.addr(),
+		MaxRetries:  -1,
+		ReadTimeout: -1,
+		CircuitBreaker: &redis.CircuitBreakerOptions{
+			MinRequests: 2,
+			CoolDown:    50 * time.Millisecond,
+			IsFailure: func(err error) bool {
+				return errors.Is(err, redis.ErrMasterDown)
+			},
+		},
+	})
+	defer c.Close()
+	ctx := context.Background()
+
+	for range 2 {
+		c.Do(ctx, "FAIL")
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	// A blocking probe takes the only slot...
+	probed := make(chan error, 1)
+	go func() {
+		_, err := c.Do(ctx, "SLOW")
+		probed <- err
+	}()
+	defer func() {
+		close(release)
+		if err := <-probed; err != nil {
+			t.Errorf("SLOW: %v", err)
+		}
+	}()
+	for c.PoolStats().Idle != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, redis.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	// ...until the cool-down has passed again.
+	time.Sleep(60 * time.Millisecond)
+	if got := whoami(t, c, "GET", "k"); got != "a" {
+		t.Fatalf("expected a, got %q", got)
+	}
+	if st := c.Stats(); st.Circuit != redis.CircuitClosed {
+		t.Fatalf("expected closed, got %v", st.Circuit)
+	}
+}
@@ -3,7 +3,10 @@ package redis
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -19,6 +22,14 @@ const (
 	defaultMaxConnAge   = 30 * time.Minute
 	defaultReadTimeout  = 3 * time.Second
 	defaultWriteTimeout = 3 * time.Second
+	defaultPoolTimeout  = defaultReadTimeout + time.Second
+
+	defaultMaxRetries      = 3
+	defaultMinRetryBackoff = 8 * time.Millisecond
+	defaultMaxRetryBackoff = 512 * time.Millisecond
+
+	reapInterval = 30 * time.Second
+	probeTimeout = time.Millisecond // liveness probe of an idle connection
 )
 
 // Options configures a Redis client.
@@ -27,23 +38,63 @@ type Options struct {
 	Password string
 	DB       int
 
+	// Username is the ACL user (Redis 6+). It is sent as AUTH username
+	// password, or in HELLO with Protocol 3. Empty means the default user.
+	Username string
+
+	// CredentialsProvider, if set, is called on every new connection for
+	// the username and password to authenticate with, overriding Username
+	// and Password. Use it for rotating secrets: connections dialed after a
+	// rotation pick up the new password, existing ones stay authenticated.
+	CredentialsProvider func(ctx context.Context) (username, password string, err error)
+
+	// ClientName is set with CLIENT SETNAME on every new connection, so
+	// that it shows up in CLIENT LIST.
+	ClientName string
+
+	// OnConnect, if set, is called for every new connection after AUTH,
+	// CLIENT SETNAME and SELECT. Returning an error discards the connection.
+	OnConnect func(ctx context.Context, cn *Conn) error
+
 	// PoolSize is the maximum number of connections in the pool.
 	// Default: 10.
 	PoolSize int
 
+	// PoolTimeout bounds how long a command waits for a connection when
+	// all PoolSize connections are in use, independently of the context
+	// deadline. The wait then fails with ErrPoolTimeout.
+	// Default: 4s. Set to -1 to wait until the context ends.
+	PoolTimeout time.Duration
+
+	// PoolFIFO reuses idle connections oldest first. The default (LIFO)
+	// reuses the most recently returned one, so that a small working set
+	// stays busy and surplus connections expire after IdleTimeout; FIFO
+	// spreads commands over every connection, e.g. behind a proxy.
+	PoolFIFO bool
+
 	// MinIdle is the minimum number of idle connections to keep alive.
-	// Default: 1.
+	// When set, NewClient dials them in the background and the reaper
+	// replenishes them; call Warmup to wait until they are ready.
+	// Default: 1, kept from being reaped but not dialed ahead.
 	MinIdle int
 
+	// OnWarmupError, if set, is called with the error of a background dial
+	// of MinIdle connections, at construction or when the reaper
+	// replenishes the pool. Such errors are otherwise only counted in
+	// Stats().DialErrors.
+	OnWarmupError func(err error)
+
 	// DialTimeout is the timeout for establishing new connections.
 	// Default: 5s.
 	DialTimeout time.Duration
 
-	// ReadTimeout is the per-command read deadline.
+	// ReadTimeout is the per-command read deadline. The context deadline
+	// applies instead when it comes first.
 	// Default: 3s. Set to -1 to disable.
 	ReadTimeout time.Duration
 
-	// WriteTimeout is the per-command write deadline.
+	// WriteTimeout is the per-command write deadline. The context deadline
+	// applies instead when it comes first.
 	// Default: 3s. Set to -1 to disable.
 	WriteTimeout time.Duration
 
@@ -51,10 +102,73 @@ type Options struct {
 	// Default: 5m. Set to -1 to disable.
 	IdleTimeout time.Duration
 
+	// HealthCheckInterval enables health checks of idle connections, for
+	// networks where a NAT or load balancer silently drops idle sessions.
+	// Connections idle for longer are probed with a non-blocking read
+	// before reuse, and the reaper sends PING on idle connections every
+	// interval, closing those that fail.
+	// Default: 0 (disabled).
+	HealthCheckInterval time.Duration
+
 	// MaxConnAge is the maximum lifetime of a connection.
 	// Connections older than this are closed when returned to the pool.
 	// Default: 30m. Set to -1 to disable.
 	MaxConnAge time.Duration
+
+	// MaxRetries is how many times Do retries a command that failed with an
+	// error accepted by ShouldRetry, waiting between MinRetryBackoff and
+	// MaxRetryBackoff (exponential, with jitter) before each retry.
+	// Default: 3. Set to -1 to disable.
+	MaxRetries int
+
+	// MinRetryBackoff is the backoff before the first retry.
+	// Default: 8ms. Set to -1 to retry without waiting.
+	MinRetryBackoff time.Duration
+
+	// MaxRetryBackoff caps the backoff.
+	// Default: 512ms. Set to -1 to retry without waiting.
+	MaxRetryBackoff time.Duration
+
+	// ShouldRetry reports whether a command that failed with err may be
+	// retried. It replaces DefaultShouldRetry, which also honours
+	// WithIdempotent; call IsIdempotent to reuse the built-in table.
+	ShouldRetry func(err error, cmd []any) bool
+
+	// CircuitBreaker, if set, enables a circuit breaker that fails commands
+	// with ErrCircuitOpen while the server keeps failing.
+	CircuitBreaker *CircuitBreakerOptions
+
+	// Protocol is the RESP version: 2 or 3. With 3, each new connection
+	// negotiates RESP3 with HELLO (which also carries AUTH).
+	// Default: 2.
+	Protocol int
+
+	// Network is "tcp" or "unix". With "unix", Addr is the socket path.
+	// Default: "tcp".
+	Network string
+
+	// Dialer, if set, opens the network connections instead of net.Dialer,
+	// e.g. to go through a SOCKS proxy or to hand out net.Pipe ends in
+	// tests. The context carries DialTimeout. TLS, when configured, is
+	// layered on top of the returned connection.
+	Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// Tracer, if set, receives a span for every dial, command and pipeline.
+	Tracer Tracer
+
+	// TLSConfig enables TLS when non-nil. If ServerName is empty it is taken
+	// from the host being dialed, so SNI and verification follow Sentinel
+	// and Cluster redirections; set it when nodes are announced by IP but
+	// the certificate names a host. Client certificates (mTLS) go in
+	// Certificates as usual.
+	TLSConfig *tls.Config
+}
+
+func (o *Options) network() string {
+	if o.Network != "" {
+		return o.Network
+	}
+	return "tcp"
 }
 
 func (o *Options) poolSize() int {
@@ -108,6 +222,46 @@ func (o *Options) idleTimeout() time.Duration {
 	return defaultIdleTimeout
 }
 
+func (o *Options) poolTimeout() time.Duration {
+	if o.PoolTimeout > 0 {
+		return o.PoolTimeout
+	}
+	if o.PoolTimeout < 0 {
+		return 0 // disabled
+	}
+	return defaultPoolTimeout
+}
+
+func (o *Options) maxRetries() int {
+	if o.MaxRetries > 0 {
+		return o.MaxRetries
+	}
+	if o.MaxRetries < 0 {
+		return 0 // disabled
+	}
+	return defaultMaxRetries
+}
+
+func (o *Options) minRetryBackoff() time.Duration {
+	if o.MinRetryBackoff > 0 {
+		return o.MinRetryBackoff
+	}
+	if o.MinRetryBackoff < 0 {
+		return 0 // disabled
+	}
+	return defaultMinRetryBackoff
+}
+
+func (o *Options) maxRetryBackoff() time.Duration {
+	if o.MaxRetryBackoff > 0 {
+		return o.MaxRetryBackoff
+	}
+	if o.MaxRetryBackoff < 0 {
+		return 0 // disabled
+	}
+	return defaultMaxRetryBackoff
+}
+
 func (o *Options) maxConnAge() time.Duration {
 	if o.MaxConnAge > 0 {
 		return o.MaxConnAge
@@ -118,7 +272,7 @@ func (o *Options) maxConnAge() time.Duration {
 	return defaultMaxConnAge
 }
 
-// Client is a minimal Redis client that speaks RESP2.
+// Client is a minimal Redis client that speaks RESP2, or RESP3 when Options.Protocol is 3.
 type Client struct {
 	opts *Options
 
@@ -128,13 +282,131 @@ type Client struct {
 	closed   atomic.Bool
 	waiters  []chan *conn // goroutines waiting for a connection
 	closedCh chan struct{}
+	fillMu   sync.Mutex // serializes fillIdle
+
+	// addr is the server connections are dialed to (guarded by mu). It is
+	// Options.Addr, or is resolved through Sentinel and changes on failover.
+	addr     string
+	resolve  func(ctx context.Context) (string, error) // fills addr when empty
+	replica  *Client                                   // read-only commands, if set
+	failover *sentinelFailover
+	cluster  *clusterState
+
+	stats   clientStats
+	breaker *circuitBreaker        // nil unless Options.CircuitBreaker is set
+	hooks   atomic.Pointer[[]Hook] // see AddHook
+	parent  *Client                // owner of the hooks, for replica and cluster node clients
 }
 
 type conn struct {
 	nc        net.Conn
+	addr      string
 	rd        *bufio.Reader
+	wr        *bufio.Writer // used to batch pipelined commands
 	createdAt time.Time
 	usedAt    time.Time
+	written   bool // some bytes of the last command reached the socket
+}
+
+// Write writes a command to the socket, recording in written whether the
+// server may have seen it.
+func (cn *conn) Write(b []byte) (int, error) {
+	n, err := cn.nc.Write(b)
+	if n > 0 {
+		cn.written = true
+	}
+	return n, err
+}
+
+// alive probes an idle connection with a non-blocking read: on a live one
+// it times out, or finds RESP3 push data; a connection closed by the peer or
+// the network reports EOF or a reset.
+func (cn *conn) alive() bool {
+	if cn.rd.Buffered() > 0 {
+		return true
+	}
+	cn.nc.SetReadDeadline(time.Now().Add(probeTimeout))
+	_, err := cn.rd.Peek(1)
+	cn.nc.SetReadDeadline(time.Time{})
+	var ne net.Error
+	return err == nil || errors.As(err, &ne) && ne.Timeout()
+}
+
+// interruptDeadline is a deadline in the past, which fails the I/O in
+// progress at once.
+var interruptDeadline = time.Unix(1, 0)
+
+// watch interrupts the I/O in progress on cn when ctx is cancelled, by
+// moving its deadlines to the past. While watching, deadlines must be set
+// with setReadDeadline and setWriteDeadline, which keep them in the past once
+// ctx is done. The returned function stops watching and resets the
+// deadlines; it must be called once the I/O is over.
+func (cn *conn) watch(ctx context.Context) (done func()) {
+	if ctx.Done() == nil {
+		return func() { cn.nc.SetDeadline(time.Time{}) }
+	}
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		cn.nc.SetDeadline(interruptDeadline)
+		close(interrupted)
+	})
+	return func() {
+		if !stop() {
+			<-interrupted // it must not clobber the reset below
+		}
+		cn.nc.SetDeadline(time.Time{})
+	}
+}
+
+// isConnError reports whether err comes from dialing or from the I/O of a
+// connection, rather than from a reply or the context.
+func isConnError(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// setReadDeadline sets the read deadline of cn under watch(ctx). ctx is
+// checked after the deadline is set, so that a cancellation whose watcher ran
+// before is not overwritten.
+func (cn *conn) setReadDeadline(ctx context.Context, t time.Time) {
+	cn.nc.SetReadDeadline(t)
+	if ctx.Err() != nil {
+		cn.nc.SetReadDeadline(interruptDeadline)
+	}
+}
+
+// setWriteDeadline is like setReadDeadline for the write deadline.
+func (cn *conn) setWriteDeadline(ctx context.Context, t time.Time) {
+	cn.nc.SetWriteDeadline(t)
+	if ctx.Err() != nil {
+		cn.nc.SetWriteDeadline(interruptDeadline)
+	}
+}
+
+// ioDeadline returns the deadline of an I/O operation: timeout from now, or
+// the deadline of ctx if it comes first. It is zero when neither is set.
+func ioDeadline(ctx context.Context, timeout time.Duration) time.Time {
+	var t time.Time
+	if timeout > 0 {
+		t = time.Now().Add(timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (t.IsZero() || d.Before(t)) {
+		t = d
+	}
+	return t
+}
+
+// ioErr returns the context error in place of an I/O error caused by the
+// cancellation or the deadline of ctx.
+func ioErr(ctx context.Context, err error) error {
+	if cerr := ctx.Err(); cerr != nil {
+		return cerr
+	}
+	var ne net.Error
+	if d, ok := ctx.Deadline(); ok && errors.As(err, &ne) && ne.Timeout() && !time.Now().Before(d) {
+		return context.DeadlineExceeded
+	}
+	return err
 }
 
 func (cn *conn) isExpired(idleTimeout, maxAge time.Duration) bool {
@@ -150,30 +422,138 @@ func (cn *conn) isExpired(idleTimeout, maxAge time.Duration) bool {
 
 // NewClient creates a new Redis client.
 func NewClient(opts *Options) *Client {
+	c := newClient(opts)
+	c.start()
+	return c
+}
+
+func newClient(opts *Options) *Client {
 	c := &Client{
 		opts:     opts,
 		pool:     make([]*conn, 0, opts.poolSize()),
 		closedCh: make(chan struct{}),
+		addr:     opts.Addr,
+	}
+	// Built-in hooks, outermost first. Replica and cluster node clients
+	// install them too, but run the hooks of their parent instead.
+	var hooks []Hook
+	if opts.Tracer != nil {
+		hooks = append(hooks, newTracingHook(opts))
+	}
+	if opts.CircuitBreaker != nil {
+		c.breaker = newCircuitBreaker(opts.CircuitBreaker)
+		hooks = append(hooks, circuitHook{c.breaker})
+	}
+	if hooks != nil {
+		c.hooks.Store(&hooks)
 	}
-	// Start background reaper for idle/expired connections
-	go c.reaper()
 	return c
 }
 
+// start launches the background reaper and, if MinIdle is set, dials the
+// idle connections. Constructors call it once the client is set up.
+func (c *Client) start() {
+	go c.reaper()
+	if c.opts.MinIdle > 0 {
+		go c.refill()
+	}
+}
+
+// refill runs fillIdle in the background, reporting its error to
+// OnWarmupError. Warmup retries the failed dials.
+func (c *Client) refill() {
+	err := c.fillIdle(context.Background())
+	if err != nil && c.opts.OnWarmupError != nil && !c.closed.Load() {
+		c.opts.OnWarmupError(err)
+	}
+}
+
 // reaper periodically removes idle and expired connections.
 func (c *Client) reaper() {
-	ticker := time.NewTicker(30 * time.Second)
+	interval := reapInterval
+	if hc := c.opts.HealthCheckInterval; hc > 0 && hc < interval {
+		interval = hc
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			c.reapStaleConns()
+			c.reap()
 		case <-c.closedCh:
 			return
 		}
 	}
 }
 
+func (c *Client) reap() {
+	if c.cluster != nil {
+		c.cluster.lazyReload() // catch up with failovers no command ran into
+		return
+	}
+	c.reapStaleConns()
+	c.pingIdleConns()
+	if c.opts.MinIdle > 0 {
+		c.refill()
+	}
+}
+
+// Warmup dials connections until MinIdle of them are idle, after waiting
+// for the ones NewClient dials in the background, and returns the first
+// dial error. For Sentinel clients the replica pool is warmed too; for
+// Cluster clients, the pool of every master. Without MinIdle it does
+// nothing.
+func (c *Client) Warmup(ctx context.Context) error {
+	if c.closed.Load() {
+		return fmt.Errorf("redis: client is closed")
+	}
+	if c.opts.MinIdle <= 0 {
+		return nil
+	}
+	if c.cluster != nil {
+		return c.ForEachMaster(ctx, func(ctx context.Context, node *Client) error {
+			return node.Warmup(ctx)
+		})
+	}
+	if err := c.fillIdle(ctx); err != nil {
+		return err
+	}
+	if c.replica != nil {
+		return c.replica.fillIdle(ctx)
+	}
+	return nil
+}
+
+// fillIdle dials connections into the pool until MinIdle are idle or
+// PoolSize is reached. Calls are serialized so that concurrent fills do not
+// overshoot. The pool of a cluster client itself is not used and is left
+// empty.
+func (c *Client) fillIdle(ctx context.Context) error {
+	if c.cluster != nil {
+		return nil
+	}
+	c.fillMu.Lock()
+	defer c.fillMu.Unlock()
+
+	for {
+		c.mu.Lock()
+		if c.closed.Load() || len(c.pool) >= c.opts.minIdle() ||
+			int(atomic.LoadInt32(&c.active)) >= c.opts.poolSize() {
+			c.mu.Unlock()
+			return nil
+		}
+		atomic.AddInt32(&c.active, 1)
+		c.mu.Unlock()
+
+		cn, err := c.dialConn(ctx)
+		if err != nil {
+			atomic.AddInt32(&c.active, -1)
+			return err
+		}
+		c.putConn(cn)
+	}
+}
+
 func (c *Client) reapStaleConns() {
 	idleTimeout := c.opts.idleTimeout()
 	maxAge := c.opts.maxConnAge()
@@ -195,24 +575,111 @@ func (c *Client) reapStaleConns() {
 	c.pool = alive
 	c.mu.Unlock()
 
+	c.stats.staleConns.Add(uint64(len(stale)))
+	for _, cn := range stale {
+		atomic.AddInt32(&c.active, -1)
+		cn.nc.Close()
+	}
+}
+
+// pingIdleConns sends PING on the connections idle for HealthCheckInterval
+// and closes those that fail. PING does not count as use for IdleTimeout.
+func (c *Client) pingIdleConns() {
+	interval := c.opts.HealthCheckInterval
+	if interval <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	var due []*conn
+	keep := c.pool[:0]
+	for _, cn := range c.pool {
+		if time.Since(cn.usedAt) >= interval {
+			due = append(due, cn)
+		} else {
+			keep = append(keep, cn)
+		}
+	}
+	c.pool = keep
+	c.mu.Unlock()
+
+	for _, cn := range due {
+		_, err := c.execOn(context.Background(), cn, "PING")
+		c.releaseConn(cn, err)
+	}
+}
+
+// resolveAddr returns the address to dial, asking the resolver (Sentinel)
+// when it is not known yet.
+func (c *Client) resolveAddr(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	addr := c.addr
+	c.mu.Unlock()
+	if addr != "" || c.resolve == nil {
+		return addr, nil
+	}
+
+	addr, err := c.resolve(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	if c.addr == "" {
+		c.addr = addr
+	}
+	addr = c.addr
+	c.mu.Unlock()
+	return addr, nil
+}
+
+// setAddr points the client at a new server. Idle connections to the old one
+// are closed; in-use ones are closed when returned. An empty addr makes the
+// next dial resolve it again. It reports whether the address changed.
+func (c *Client) setAddr(addr string) bool {
+	c.mu.Lock()
+	if addr == c.addr {
+		c.mu.Unlock()
+		return false
+	}
+	c.addr = addr
+	stale := c.pool
+	c.pool = make([]*conn, 0, c.opts.poolSize())
+	c.mu.Unlock()
+
 	for _, cn := range stale {
 		atomic.AddInt32(&c.active, -1)
 		cn.nc.Close()
 	}
+	return true
 }
 
 func (c *Client) dialConn(ctx context.Context) (*conn, error) {
+	c.stats.dials.Add(1)
+	cn, err := c.newConn(ctx)
+	if err != nil {
+		c.stats.dialErrors.Add(1)
+	}
+	return cn, err
+}
+
+// newConn dials and initializes a connection.
+func (c *Client) newConn(ctx context.Context) (*conn, error) {
 	dialCtx, cancel := context.WithTimeout(ctx, c.opts.dialTimeout())
 	defer cancel()
 
-	var d net.Dialer
-	nc, err := d.DialContext(dialCtx, "tcp", c.opts.Addr)
+	addr, err := c.resolveAddr(dialCtx)
+	if err != nil {
+		return nil, err
+	}
+	nc, err := c.dialHook(c.dial)(dialCtx, c.opts.network(), addr)
 	if err != nil {
 		return nil, err
 	}
 	cn := &conn{
 		nc:        nc,
+		addr:      addr,
 		rd:        bufio.NewReader(nc),
+		wr:        bufio.NewWriter(nc),
 		createdAt: time.Now(),
 		usedAt:    time.Now(),
 	}
@@ -220,19 +687,113 @@ func (c *Client) dialConn(ctx context.Context) (*conn, error) {
 	initCtx, initCancel := context.WithTimeout(ctx, c.opts.dialTimeout())
 	defer initCancel()
 
-	if c.opts.Password != "" {
-		if _, err := c.execOn(initCtx, cn, "AUTH", c.opts.Password); err != nil {
-			nc.Close()
-			return nil, err
+	if err := c.initConn(initCtx, cn); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return cn, nil
+}
+
+// initConn authenticates a new connection, names it, selects the DB and
+// runs Options.OnConnect.
+func (c *Client) initConn(ctx context.Context, cn *conn) error {
+	o := c.opts
+	user, pass := o.Username, o.Password
+	if o.CredentialsProvider != nil {
+		var err error
+		if user, pass, err = o.CredentialsProvider(ctx); err != nil {
+			return fmt.Errorf("redis: credentials provider: %w", err)
 		}
 	}
-	if c.opts.DB != 0 {
-		if _, err := c.execOn(initCtx, cn, "SELECT", c.opts.DB); err != nil {
-			nc.Close()
-			return nil, err
+
+	if o.Protocol == 3 {
+		args := []any{"HELLO", 3}
+		if pass != "" {
+			if user == "" {
+				user = "default"
+			}
+			args = append(args, "AUTH", user, pass)
+		}
+		if o.ClientName != "" {
+			args = append(args, "SETNAME", o.ClientName)
+		}
+		if _, err := c.execOn(ctx, cn, args...); err != nil {
+			return err
+		}
+	} else {
+		if pass != "" {
+			args := []any{"AUTH", pass}
+			if user != "" {
+				args = []any{"AUTH", user, pass}
+			}
+			if _, err := c.execOn(ctx, cn, args...); err != nil {
+				return err
+			}
+		}
+		if o.ClientName != "" {
+			if _, err := c.execOn(ctx, cn, "CLIENT", "SETNAME", o.ClientName); err != nil {
+				return err
+			}
 		}
 	}
-	return cn, nil
+	if o.DB != 0 {
+		if _, err := c.execOn(ctx, cn, "SELECT", o.DB); err != nil {
+			return err
+		}
+	}
+	if o.OnConnect != nil {
+		if err := o.OnConnect(ctx, &Conn{c: c, cn: cn}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Conn is a single new connection, passed to Options.OnConnect for extra
+// initialization. It is only valid inside the callback.
+type Conn struct {
+	c  *Client
+	cn *conn
+}
+
+// Do executes a raw command on this connection.
+func (cn *Conn) Do(ctx context.Context, args ...any) (any, error) {
+	return cn.c.execOn(ctx, cn.cn, args...)
+}
+
+// RemoteAddr returns the address of the server the connection is open to.
+func (cn *Conn) RemoteAddr() string {
+	return cn.cn.addr
+}
+
+// dial opens a connection to addr with Options.Dialer or net.Dialer, then
+// performs the TLS handshake when Options.TLSConfig is set.
+func (c *Client) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := c.opts.Dialer
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	nc, err := dial(ctx, network, addr)
+	if err != nil || c.opts.TLSConfig == nil {
+		return nc, err
+	}
+
+	cfg := c.opts.TLSConfig
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		cfg = cfg.Clone()
+		cfg.ServerName = host
+	}
+	tc := tls.Client(nc, cfg)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return tc, nil
 }
 
 func (c *Client) getConn(ctx context.Context) (*conn, error) {
@@ -246,24 +807,40 @@ func (c *Client) getConn(ctx context.Context) (*conn, error) {
 	idleTimeout := c.opts.idleTimeout()
 	maxAge := c.opts.maxConnAge()
 	for len(c.pool) > 0 {
-		cn := c.pool[len(c.pool)-1]
-		c.pool = c.pool[:len(c.pool)-1]
+		var cn *conn
+		if c.opts.PoolFIFO {
+			cn = c.pool[0]
+			c.pool = c.pool[1:]
+		} else {
+			cn = c.pool[len(c.pool)-1]
+			c.pool = c.pool[:len(c.pool)-1]
+		}
+
+		c.mu.Unlock()
 
 		if cn.isExpired(idleTimeout, maxAge) {
-			c.mu.Unlock()
+			c.stats.staleConns.Add(1)
 			atomic.AddInt32(&c.active, -1)
 			cn.nc.Close()
 			c.mu.Lock()
 			continue
 		}
-		c.mu.Unlock()
+		if hc := c.opts.HealthCheckInterval; hc > 0 && time.Since(cn.usedAt) > hc && !cn.alive() {
+			c.stats.errorConns.Add(1)
+			atomic.AddInt32(&c.active, -1)
+			cn.nc.Close()
+			c.mu.Lock()
+			continue
+		}
+		c.stats.hits.Add(1)
 		cn.usedAt = time.Now()
 		return cn, nil
 	}
+	c.stats.misses.Add(1)
 
 	// Can we create a new connection?
-	if int(c.active) < c.opts.poolSize() {
-		c.active++
+	if int(atomic.LoadInt32(&c.active)) < c.opts.poolSize() {
+		atomic.AddInt32(&c.active, 1)
 		c.mu.Unlock()
 		cn, err := c.dialConn(ctx)
 		if err != nil {
@@ -278,6 +855,17 @@ func (c *Client) getConn(ctx context.Context) (*conn, error) {
 	c.waiters = append(c.waiters, ch)
 	c.mu.Unlock()
 
+	start := time.Now()
+	defer func() { c.stats.wait.observe(time.Since(start)) }()
+
+	var timeout <-chan time.Time
+	if d := c.opts.poolTimeout(); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
 	select {
 	case cn := <-ch:
 		if cn == nil {
@@ -286,23 +874,33 @@ func (c *Client) getConn(ctx context.Context) (*conn, error) {
 		cn.usedAt = time.Now()
 		return cn, nil
 	case <-ctx.Done():
-		// Remove ourselves from waiters
-		c.mu.Lock()
-		for i, w := range c.waiters {
-			if w == ch {
-				c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
-				break
-			}
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrPoolTimeout
+	}
+	// Remove ourselves from waiters
+	c.mu.Lock()
+	for i, w := range c.waiters {
+		if w == ch {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			break
 		}
-		c.mu.Unlock()
-		// Drain channel in case a connection arrived
-		select {
-		case cn := <-ch:
+	}
+	c.mu.Unlock()
+	// Drain channel in case a connection arrived
+	select {
+	case cn := <-ch:
+		if cn != nil {
 			c.putConn(cn)
-		default:
 		}
-		return nil, ctx.Err()
+	default:
 	}
+	if errors.Is(err, context.Canceled) {
+		c.stats.waitCancels.Add(1)
+	} else {
+		c.stats.timeouts.Add(1)
+	}
+	return nil, err
 }
 
 func (c *Client) putConn(cn *conn) {
@@ -314,6 +912,17 @@ func (c *Client) putConn(cn *conn) {
 
 	c.mu.Lock()
 
+	// Check if connection should be retired, before a waiter gets it: after
+	// a failover it points at the old master. releaseSlot dials a
+	// replacement for the waiter.
+	if cn.addr != c.addr || cn.isExpired(c.opts.idleTimeout(), c.opts.maxConnAge()) {
+		c.mu.Unlock()
+		c.stats.staleConns.Add(1)
+		cn.nc.Close()
+		c.releaseSlot()
+		return
+	}
+
 	// If someone is waiting, hand the connection directly
 	if len(c.waiters) > 0 {
 		ch := c.waiters[0]
@@ -323,21 +932,37 @@ func (c *Client) putConn(cn *conn) {
 		return
 	}
 
-	// Check if connection should be retired
-	if cn.isExpired(c.opts.idleTimeout(), c.opts.maxConnAge()) {
-		c.mu.Unlock()
-		atomic.AddInt32(&c.active, -1)
-		cn.nc.Close()
-		return
-	}
-
 	c.pool = append(c.pool, cn)
 	c.mu.Unlock()
 }
 
 func (c *Client) removeConn(cn *conn) {
-	atomic.AddInt32(&c.active, -1)
+	c.stats.errorConns.Add(1)
 	cn.nc.Close()
+	c.releaseSlot()
+}
+
+// releaseSlot frees the pool slot of an in-use connection that was closed
+// instead of returned. A waiter would otherwise keep waiting for a
+// connection that never comes back, so if there is one, the slot is kept
+// and a replacement is dialed for it.
+func (c *Client) releaseSlot() {
+	c.mu.Lock()
+	waiting := len(c.waiters) > 0 && !c.closed.Load()
+	c.mu.Unlock()
+	if !waiting {
+		atomic.AddInt32(&c.active, -1)
+		return
+	}
+
+	go func() {
+		cn, err := c.dialConn(context.Background())
+		if err != nil {
+			atomic.AddInt32(&c.active, -1)
+			return
+		}
+		c.putConn(cn)
+	}()
 }
 
 func (c *Client) execOn(ctx context.Context, cn *conn, args ...any) (any, error) {
@@ -346,52 +971,137 @@ func (c *Client) execOn(ctx context.Context, cn *conn, args ...any) (any, error)
 		return nil, ctx.Err()
 	default:
 	}
+	defer cn.watch(ctx)()
 
-	wt := c.opts.writeTimeout()
-	if wt > 0 {
-		cn.nc.SetWriteDeadline(time.Now().Add(wt))
-	}
-	if err := WriteCommand(cn.nc, args...); err != nil {
-		return nil, err
+	cn.setWriteDeadline(ctx, ioDeadline(ctx, c.opts.writeTimeout()))
+	cn.written = false
+	if err := WriteCommand(cn, args...); err != nil {
+		return nil, ioErr(ctx, err)
 	}
 
 	rt := c.opts.readTimeout()
-	if rt > 0 {
-		cn.nc.SetReadDeadline(time.Now().Add(rt))
+	if block, ok := ctx.Value(blockKey{}).(time.Duration); ok && rt > 0 {
+		if block > 0 {
+			rt += block
+		} else {
+			rt = 0 // blocks indefinitely
+		}
 	}
-	reply, err := ReadReply(cn.rd)
+	cn.setReadDeadline(ctx, ioDeadline(ctx, rt))
+	reply, err := readReply(cn)
 	if err != nil {
-		return nil, err
+		return nil, ioErr(ctx, err)
 	}
 
-	// Reset deadlines
-	cn.nc.SetDeadline(time.Time{})
-
 	if e, ok := reply.(RedisError); ok {
 		return nil, e
 	}
 	return reply, nil
 }
 
+// blockKey marks a context used for a blocking command (BZPOPMIN, XREAD BLOCK, ...).
+type blockKey struct{}
+
+// withBlock extends the read deadline of the command executed with ctx by the
+// server-side blocking timeout d. A zero d blocks indefinitely.
+func withBlock(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, blockKey{}, d)
+}
+
+// readReply reads the next command reply from cn, skipping RESP3 push frames
+// (e.g. client-side caching invalidations) that may arrive in between.
+func readReply(cn *conn) (any, error) {
+	for {
+		reply, err := ReadReply(cn.rd)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := reply.(Push); ok {
+			continue
+		}
+		return reply, nil
+	}
+}
+
 // Do executes a raw Redis command and returns the reply.
 func (c *Client) Do(ctx context.Context, args ...any) (any, error) {
+	start := time.Now()
+	reply, err := c.processHook(c.retry)(ctx, args)
+	c.root().stats.observeCommand(commandName(args), time.Since(start))
+	return reply, err
+}
+
+// process runs a command once, without hooks or retries, routing it to a cluster node or a
+// replica when applicable.
+func (c *Client) process(ctx context.Context, args []any) (any, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 	}
 
+	if c.cluster != nil {
+		return c.cluster.do(ctx, args)
+	}
+	if c.replica != nil && isReadOnly(args) {
+		reply, err := c.replica.process(ctx, args)
+		if err == nil || !isConnError(err) || ctx.Err() != nil {
+			return reply, err
+		}
+		// The replica is unreachable: ask Sentinel for another one on the
+		// next dial, and read from the master meanwhile.
+		c.replica.setAddr("")
+	}
+
 	cn, err := c.getConn(ctx)
 	if err != nil {
 		return nil, err
 	}
 	reply, err := c.execOn(ctx, cn, args...)
+	written := cn.written
+	c.releaseConn(cn, err)
+
+	// A pooled connection that was dead before the command reached it:
+	// retry an idempotent command once on a new connection, as the other
+	// idle ones may be dead too.
+	if err != nil && !written && ctx.Err() == nil && isIdempotent(ctx, args) {
+		if cn, err = c.freshConn(ctx); err != nil {
+			return nil, err
+		}
+		reply, err = c.execOn(ctx, cn, args...)
+		c.releaseConn(cn, err)
+	}
+	return reply, err
+}
+
+// freshConn dials a connection without looking at the idle pool, or waits
+// for one if the pool is full.
+func (c *Client) freshConn(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	if int(atomic.LoadInt32(&c.active)) >= c.opts.poolSize() {
+		c.mu.Unlock()
+		return c.getConn(ctx)
+	}
+	atomic.AddInt32(&c.active, 1)
+	c.mu.Unlock()
+
+	cn, err := c.dialConn(ctx)
 	if err != nil {
-		c.removeConn(cn) // discard broken connection
+		atomic.AddInt32(&c.active, -1)
 		return nil, err
 	}
+	return cn, nil
+}
+
+// releaseConn returns cn to the pool after a command. An error reply leaves
+// the connection usable; any other error discards it.
+func (c *Client) releaseConn(cn *conn, err error) {
+	var rerr RedisError
+	if err != nil && !errors.As(err, &rerr) {
+		c.removeConn(cn) // discard broken connection
+		return
+	}
 	c.putConn(cn)
-	return reply, nil
 }
 
 // Close releases all pooled connections and stops the background reaper.
@@ -401,6 +1111,16 @@ func (c *Client) Close() error {
 	}
 	close(c.closedCh)
 
+	if c.failover != nil {
+		c.failover.close()
+	}
+	if c.cluster != nil {
+		c.cluster.close()
+	}
+	if c.replica != nil {
+		c.replica.Close()
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -446,24 +1166,12 @@ func (c *Client) Ping(ctx context.Context) error {
 
 // Eval executes a Lua script via EVAL.
 func (c *Client) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
-	cmd := make([]any, 0, 3+len(keys)+len(args))
-	cmd = append(cmd, "EVAL", script, len(keys))
-	for _, k := range keys {
-		cmd = append(cmd, k)
-	}
-	cmd = append(cmd, args...)
-	return c.Do(ctx, cmd...)
+	return c.Do(ctx, scriptArgs("EVAL", script, keys, args)...)
 }
 
 // EvalSha executes a cached Lua script via EVALSHA.
 func (c *Client) EvalSha(ctx context.Context, sha string, keys []string, args ...any) (any, error) {
-	cmd := make([]any, 0, 3+len(keys)+len(args))
-	cmd = append(cmd, "EVALSHA", sha, len(keys))
-	for _, k := range keys {
-		cmd = append(cmd, k)
-	}
-	cmd = append(cmd, args...)
-	return c.Do(ctx, cmd...)
+	return c.Do(ctx, scriptArgs("EVALSHA", sha, keys, args)...)
 }
 
 // ScriptLoad loads a Lua script into the script cache and returns its SHA1.
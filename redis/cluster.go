@@ -0,0 +1,522 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	clusterSlots               = 16384
+	defaultClusterMaxRedirects = 3
+)
+
+// ClusterOptions configures a Redis Cluster client.
+type ClusterOptions struct {
+	// Addrs are seed nodes used to discover the cluster layout.
+	Addrs []string
+
+	// MaxRedirects is how many MOVED/ASK redirections a command follows.
+	// Default: 3.
+	MaxRedirects int
+
+	// Options configures the pool of each node. Addr and DB are ignored:
+	// a cluster only has database 0.
+	Options
+}
+
+func (o *ClusterOptions) maxRedirects() int {
+	if o.MaxRedirects > 0 {
+		return o.MaxRedirects
+	}
+	return defaultClusterMaxRedirects
+}
+
+// NewClusterClient creates a client for Redis Cluster. The slot layout is
+// discovered lazily with CLUSTER SLOTS from the seed nodes. It is refreshed
+// when a MOVED redirection is received, when a node cannot be reached or
+// replies CLUSTERDOWN or READONLY, and every 30 seconds.
+//
+// Commands sent through Do and the typed helpers are routed by the hash slot
+// of their first key; keyless commands go to a random master. SCRIPT and
+// FLUSHALL/FLUSHDB are sent to every master. A pipeline, transaction or Watch
+// runs on the node owning its first key, so all of its keys must share a
+// slot (use hash tags such as "{user:1}:name"). Scan iterates a single
+// master, picked for its first page; use ForEachMaster to scan the whole
+// cluster.
+func NewClusterClient(opts *ClusterOptions) *Client {
+	cs := &clusterState{
+		opts:  opts,
+		seeds: append([]string(nil), opts.Addrs...),
+		nodes: make(map[string]*Client),
+	}
+	o := opts.Options
+	o.Addr = ""
+	o.DB = 0
+	c := newClient(&o)
+	c.resolve = cs.anyMasterAddr
+	c.cluster = cs
+	cs.root = c
+	c.start()
+	return c
+}
+
+// IsCluster reports whether c was created by NewClusterClient.
+func (c *Client) IsCluster() bool { return c.cluster != nil }
+
+// ForEachMaster calls fn for the client of every master node. For a
+// non-cluster client, fn is called once with c itself.
+func (c *Client) ForEachMaster(ctx context.Context, fn func(ctx context.Context, node *Client) error) error {
+	if c.cluster == nil {
+		return fn(ctx, c)
+	}
+	masters, err := c.cluster.masterNodes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, node := range masters {
+		if err := fn(ctx, node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clusterState holds the slot layout and the per-node clients of a cluster client.
+type clusterState struct {
+	opts  *ClusterOptions
+	seeds []string
+	root  *Client // the cluster client, owner of the hooks
+
+	mu      sync.RWMutex
+	slots   [clusterSlots]string // master address per slot, "" if unknown
+	masters []string
+	nodes   map[string]*Client // every node seen, including redirection targets
+	loaded  bool
+	closed  bool
+
+	reloading atomic.Bool
+}
+
+// node returns the client for addr, creating it on first use.
+func (cs *clusterState) node(addr string) (*Client, error) {
+	cs.mu.RLock()
+	node, ok := cs.nodes[addr]
+	closed := cs.closed
+	cs.mu.RUnlock()
+	if ok {
+		return node, nil
+	}
+	if closed {
+		return nil, fmt.Errorf("redis: client is closed")
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if node, ok := cs.nodes[addr]; ok {
+		return node, nil
+	}
+	o := cs.opts.Options
+	o.Addr = addr
+	o.DB = 0
+	node = newClient(&o)
+	node.parent = cs.root
+	node.start()
+	cs.nodes[addr] = node
+	return node, nil
+}
+
+// load fetches the slot layout from the known masters or the seed nodes.
+func (cs *clusterState) load(ctx context.Context) error {
+	cs.mu.RLock()
+	addrs := append(append([]string(nil), cs.masters...), cs.seeds...)
+	cs.mu.RUnlock()
+
+	var lastErr error
+	for _, addr := range addrs {
+		node, err := cs.node(addr)
+		if err != nil {
+			return err
+		}
+		reply, err := node.process(ctx, []any{"CLUSTER", "SLOTS"})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err := cs.setSlots(reply); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no seed addresses")
+	}
+	return fmt.Errorf("redis: cluster: %w", lastErr)
+}
+
+// setSlots applies a CLUSTER SLOTS reply:
+// [[start, end, [host, port, id, ...], replicas...], ...].
+func (cs *clusterState) setSlots(reply any) error {
+	ranges, ok := reply.([]any)
+	if !ok {
+		return fmt.Errorf("unexpected type %T from CLUSTER SLOTS", reply)
+	}
+	var slots [clusterSlots]string
+	seen := make(map[string]bool)
+	var masters []string
+	for _, r := range ranges {
+		arr, ok := r.([]any)
+		if !ok || len(arr) < 3 {
+			return fmt.Errorf("unexpected slot range %v from CLUSTER SLOTS", r)
+		}
+		start, _ := arr[0].(int64)
+		end, _ := arr[1].(int64)
+		master, ok := arr[2].([]any)
+		if !ok || len(master) < 2 || start < 0 || end >= clusterSlots || start > end {
+			return fmt.Errorf("unexpected slot range %v from CLUSTER SLOTS", r)
+		}
+		host, _ := master[0].(string)
+		port, _ := master[1].(int64)
+		addr := net.JoinHostPort(host, strconv.FormatInt(port, 10))
+		for s := start; s <= end; s++ {
+			slots[s] = addr
+		}
+		if !seen[addr] {
+			seen[addr] = true
+			masters = append(masters, addr)
+		}
+	}
+
+	cs.mu.Lock()
+	cs.slots = slots
+	cs.masters = masters
+	cs.loaded = true
+	cs.mu.Unlock()
+	return nil
+}
+
+func (cs *clusterState) ensureLoaded(ctx context.Context) error {
+	cs.mu.RLock()
+	loaded := cs.loaded
+	cs.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+	return cs.load(ctx)
+}
+
+// lazyReload refreshes the slot layout in the background, at most once at a time.
+func (cs *clusterState) lazyReload() {
+	if !cs.reloading.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer cs.reloading.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), cs.opts.dialTimeout())
+		defer cancel()
+		cs.load(ctx)
+	}()
+}
+
+// needsReload reports whether err suggests that the slot layout changed: the
+// node is unreachable, e.g. a failed master, or lost its slots.
+func needsReload(err error) bool {
+	return errors.Is(err, ErrClusterDown) || errors.Is(err, ErrReadOnly) || isConnError(err)
+}
+
+// slotNode returns the master for slot, or a random master when slot is -1.
+func (cs *clusterState) slotNode(ctx context.Context, slot int) (*Client, error) {
+	if err := cs.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+	cs.mu.RLock()
+	var addr string
+	if slot >= 0 {
+		addr = cs.slots[slot]
+	}
+	if addr == "" && len(cs.masters) > 0 {
+		addr = cs.masters[rand.IntN(len(cs.masters))]
+	}
+	cs.mu.RUnlock()
+	if addr == "" {
+		return nil, fmt.Errorf("redis: cluster: no node serves slot %d", slot)
+	}
+	return cs.node(addr)
+}
+
+// argsNode returns the node for a command, routed by its first key.
+func (cs *clusterState) argsNode(ctx context.Context, args []any) (*Client, error) {
+	slot := -1
+	if key, ok := firstKey(args); ok {
+		slot = HashSlot(key)
+	}
+	return cs.slotNode(ctx, slot)
+}
+
+// cmdsNode returns the node for a pipeline, routed by its first keyed command.
+func (cs *clusterState) cmdsNode(ctx context.Context, cmds []*Cmd) (*Client, error) {
+	for _, cmd := range cmds {
+		if key, ok := firstKey(cmd.args); ok {
+			return cs.slotNode(ctx, HashSlot(key))
+		}
+	}
+	return cs.slotNode(ctx, -1)
+}
+
+func (cs *clusterState) anyMasterAddr(ctx context.Context) (string, error) {
+	node, err := cs.slotNode(ctx, -1)
+	if err != nil {
+		return "", err
+	}
+	return node.opts.Addr, nil
+}
+
+func (cs *clusterState) masterNodes(ctx context.Context) ([]*Client, error) {
+	if err := cs.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+	cs.mu.RLock()
+	addrs := append([]string(nil), cs.masters...)
+	cs.mu.RUnlock()
+	nodes := make([]*Client, 0, len(addrs))
+	for _, addr := range addrs {
+		node, err := cs.node(addr)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// do routes a command to its node and follows MOVED and ASK redirections.
+func (cs *clusterState) do(ctx context.Context, args []any) (any, error) {
+	if isBroadcast(args) {
+		return cs.broadcast(ctx, args)
+	}
+	node, err := cs.argsNode(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	asking := false
+	for attempt := 0; ; attempt++ {
+		var reply any
+		if asking {
+			reply, err = node.doAsking(ctx, args)
+		} else {
+			reply, err = node.process(ctx, args)
+		}
+		if err != nil && needsReload(err) {
+			cs.lazyReload()
+		}
+		var rerr RedisError
+		if err == nil || attempt >= cs.opts.maxRedirects() || !errors.As(err, &rerr) {
+			return reply, err
+		}
+
+		switch {
+		case errors.Is(rerr, ErrMoved):
+			slot, addr, ok := parseRedirect(rerr)
+			if !ok {
+				return nil, err
+			}
+			cs.mu.Lock()
+			cs.slots[slot] = addr
+			cs.mu.Unlock()
+			cs.lazyReload()
+			asking = false
+			node, err = cs.node(addr)
+		case errors.Is(rerr, ErrAsk):
+			_, addr, ok := parseRedirect(rerr)
+			if !ok {
+				return nil, err
+			}
+			asking = true
+			node, err = cs.node(addr)
+		default:
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// broadcast runs a command on every master. SCRIPT EXISTS replies are
+// combined so that a script only counts as loaded if every master has it;
+// otherwise the first master's reply is returned.
+func (cs *clusterState) broadcast(ctx context.Context, args []any) (any, error) {
+	masters, err := cs.masterNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exists := isScriptExists(args)
+	var result any
+	for i, node := range masters {
+		reply, err := node.process(ctx, args)
+		if err != nil {
+			return nil, fmt.Errorf("redis: cluster node %s: %w", node.opts.Addr, err)
+		}
+		if i == 0 {
+			result = reply
+			continue
+		}
+		if exists {
+			acc, _ := result.([]any)
+			arr, _ := reply.([]any)
+			for j := range acc {
+				if j >= len(arr) || arr[j] != int64(1) {
+					acc[j] = int64(0)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// doAsking sends ASKING followed by the command on the same connection.
+func (c *Client) doAsking(ctx context.Context, args []any) (any, error) {
+	cn, err := c.getConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.execOn(ctx, cn, "ASKING"); err != nil {
+		c.releaseConn(cn, err)
+		return nil, err
+	}
+	reply, err := c.execOn(ctx, cn, args...)
+	c.releaseConn(cn, err)
+	return reply, err
+}
+
+func (cs *clusterState) close() {
+	cs.mu.Lock()
+	cs.closed = true
+	nodes := cs.nodes
+	cs.nodes = make(map[string]*Client)
+	cs.mu.Unlock()
+	for _, node := range nodes {
+		node.Close()
+	}
+}
+
+// parseRedirect parses "MOVED <slot> <addr>" or "ASK <slot> <addr>".
+func parseRedirect(err RedisError) (int, string, bool) {
+	parts := strings.Fields(err.Message())
+	if len(parts) != 2 {
+		return 0, "", false
+	}
+	slot, perr := strconv.Atoi(parts[0])
+	if perr != nil || slot < 0 || slot >= clusterSlots {
+		return 0, "", false
+	}
+	return slot, parts[1], true
+}
+
+func isBroadcast(args []any) bool {
+	switch commandName(args) {
+	case "FLUSHALL", "FLUSHDB", "SCRIPT":
+		return true
+	}
+	return false
+}
+
+func isScriptExists(args []any) bool {
+	if len(args) < 2 || commandName(args) != "SCRIPT" {
+		return false
+	}
+	sub, _ := args[1].(string)
+	return strings.EqualFold(sub, "EXISTS")
+}
+
+func commandName(args []any) string {
+	if len(args) == 0 {
+		return ""
+	}
+	name, _ := args[0].(string)
+	return strings.ToUpper(name)
+}
+
+// keylessCommands are routed to a random master.
+var keylessCommands = map[string]bool{
+	"PING": true, "ECHO": true, "INFO": true, "TIME": true, "DBSIZE": true,
+	"RANDOMKEY": true, "KEYS": true, "SCAN": true, "CLUSTER": true, "CLIENT": true,
+	"CONFIG": true, "COMMAND": true, "PUBLISH": true, "PUBSUB": true,
+	"WAIT": true, "READONLY": true, "READWRITE": true,
+}
+
+// firstKey returns the first key of a command, if any.
+func firstKey(args []any) (string, bool) {
+	name := commandName(args)
+	if name == "" || keylessCommands[name] {
+		return "", false
+	}
+	idx := 1
+	switch name {
+	case "EVAL", "EVALSHA", "EVAL_RO", "EVALSHA_RO", "FCALL", "FCALL_RO":
+		// name script numkeys key...
+		if len(args) < 4 || fmt.Sprint(args[2]) == "0" {
+			return "", false
+		}
+		idx = 3
+	case "ZUNION", "ZINTER", "ZDIFF":
+		// name numkeys key...
+		idx = 2
+	case "XGROUP", "XINFO", "OBJECT", "MEMORY":
+		// name subcommand key...
+		idx = 2
+	case "XREAD", "XREADGROUP":
+		idx = -1
+		for i, a := range args {
+			if s, ok := a.(string); ok && strings.EqualFold(s, "STREAMS") {
+				idx = i + 1
+				break
+			}
+		}
+	}
+	if idx < 0 || idx >= len(args) {
+		return "", false
+	}
+	switch k := args[idx].(type) {
+	case string:
+		return k, true
+	case []byte:
+		return string(k), true
+	}
+	return fmt.Sprint(args[idx]), true
+}
+
+// HashSlot returns the cluster hash slot of key. Only the part inside the
+// first non-empty {...} hash tag is hashed, so keys sharing a tag share a slot.
+func HashSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlots)
+}
+
+// crc16 is CRC-16/XMODEM, the checksum used for cluster key hashing.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,353 @@
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+func TestHashSlot(t *testing.T) {
+	tests := []struct {
+		key  string
+		slot int
+	}{
+		{"123456789", 0x31C3}, // CRC-16/XMODEM check value
+		{"foo", 12182},
+		{"bar", 5061},
+		{"hello", 866},
+	}
+	for _, tt := range tests {
+		if got := redis.HashSlot(tt.key); got != tt.slot {
+			t.Errorf("HashSlot(%q) = %d, want %d", tt.key, got, tt.slot)
+		}
+	}
+
+	same := [][2]string{
+		{"{user1000}.following", "user1000"},
+		{"foo{bar}{zap}", "bar"},
+		{"foo{{bar}}zap", "{bar"},
+		{"foo{}{bar}", "foo{}{bar}"}, // empty tag: the whole key is hashed
+	}
+	for _, p := range same {
+		if redis.HashSlot(p[0]) != redis.HashSlot(p[1]) {
+			t.Errorf("HashSlot(%q) != HashSlot(%q)", p[0], p[1])
+		}
+	}
+}
+
+// fakeCluster is a set of fake nodes sharing a slot layout. Nodes reply to
+// keyed commands with their name, or with MOVED/ASK redirections.
+type fakeCluster struct {
+	mu    sync.Mutex
+	owner [16384]*fakeNode
+	ask   map[int]*fakeNode // slot being imported by another node
+	moved int               // MOVED replies sent
+}
+
+type fakeNode struct {
+	*fakeServer
+	name    string
+	fc      *fakeCluster
+	asking  bool
+	scripts int
+	hasSha  bool
+}
+
+func newFakeCluster(t *testing.T, names ...string) (*fakeCluster, []*fakeNode) {
+	fc := &fakeCluster{ask: make(map[int]*fakeNode)}
+	nodes := make([]*fakeNode, len(names))
+	per := 16384 / len(names)
+	for i, name := range names {
+		n := &fakeNode{name: name, fc: fc}
+		n.fakeServer = newFakeServer(t, n.handle)
+		nodes[i] = n
+		for s := i * per; s < (i+1)*per || (i == len(names)-1 && s < 16384); s++ {
+			fc.owner[s] = n
+		}
+	}
+	return fc, nodes
+}
+
+func (fc *fakeCluster) move(slot int, to *fakeNode) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.owner[slot] = to
+}
+
+func (fc *fakeCluster) slotsReply() string {
+	var ranges []string
+	for s := 0; s < 16384; {
+		n := fc.owner[s]
+		e := s
+		for e+1 < 16384 && fc.owner[e+1] == n {
+			e++
+		}
+		host, port := n.hostPort()
+		ranges = append(ranges, fmt.Sprintf("*3\r\n:%d\r\n:%d\r\n*2\r\n%s:%s\r\n", s, e, bulk(host), port))
+		s = e + 1
+	}
+	return fmt.Sprintf("*%d\r\n%s", len(ranges), strings.Join(ranges, ""))
+}
+
+func (n *fakeNode) handle(args []string) string {
+	fc := n.fc
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "CLUSTER":
+		return fc.slotsReply()
+	case "ASKING":
+		n.asking = true
+		return "+OK\r\n"
+	case "SCRIPT":
+		if strings.EqualFold(args[1], "EXISTS") {
+			if n.hasSha {
+				return "*1\r\n:1\r\n"
+			}
+			return "*1\r\n:0\r\n"
+		}
+		n.scripts++
+		return bulk("sha")
+	case "SCAN":
+		// Three pages of one key named after the node.
+		cursor, _ := strconv.Atoi(args[1])
+		next := (cursor + 1) % 3
+		return fmt.Sprintf("*2\r\n%s*1\r\n%s", bulk(strconv.Itoa(next)), bulk(fmt.Sprintf("%s%d", n.name, cursor)))
+	}
+
+	if len(args) < 2 {
+		return bulk(n.name) // keyless
+	}
+	key := args[1]
+	switch strings.ToUpper(args[0]) {
+	case "EVALSHA":
+		key = args[3]
+	case "XGROUP", "XINFO":
+		key = args[2]
+	}
+	slot := redis.HashSlot(key)
+	owner := fc.owner[slot]
+	if target := fc.ask[slot]; target != nil {
+		if n == owner {
+			return fmt.Sprintf("-ASK %d %s\r\n", slot, target.addr())
+		}
+		if n == target && n.asking {
+			n.asking = false
+			return bulk(n.name)
+		}
+	}
+	if n != owner {
+		fc.moved++
+		return fmt.Sprintf("-MOVED %d %s\r\n", slot, owner.addr())
+	}
+	return bulk(n.name)
+}
+
+func newFakeClusterClient(t *testing.T, seeds ...*fakeNode) *redis.Client {
+	addrs := make([]string, len(seeds))
+	for i, n := range seeds {
+		addrs[i] = n.addr()
+	}
+	c := redis.NewClusterClient(&redis.ClusterOptions{Addrs: addrs})
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClusterClient_Routing(t *testing.T) {
+	fc, nodes := newFakeCluster(t, "a", "b")
+	c := newFakeClusterClient(t, nodes[1])
+
+	if !c.IsCluster() {
+		t.Fatal("expected a cluster client")
+	}
+	if got := whoami(t, c, "GET", "bar"); got != "a" { // slot 5061
+		t.Fatalf("GET bar: expected a, got %q", got)
+	}
+	if got := whoami(t, c, "GET", "foo"); got != "b" { // slot 12182
+		t.Fatalf("GET foo: expected b, got %q", got)
+	}
+	if got := whoami(t, c, "EVALSHA", "sha", 1, "bar"); got != "a" {
+		t.Fatalf("EVALSHA: expected a, got %q", got)
+	}
+	if got := whoami(t, c, "XGROUP", "CREATE", "foo", "g", "$"); got != "b" {
+		t.Fatalf("XGROUP: expected b, got %q", got)
+	}
+	if got := whoami(t, c, "XINFO", "STREAM", "bar"); got != "a" {
+		t.Fatalf("XINFO: expected a, got %q", got)
+	}
+	fc.mu.Lock()
+	moved := fc.moved
+	fc.mu.Unlock()
+	if moved != 0 {
+		t.Fatalf("expected no MOVED redirection, got %d", moved)
+	}
+
+	var masters []string
+	err := c.ForEachMaster(context.Background(), func(ctx context.Context, node *redis.Client) error {
+		masters = append(masters, whoami(t, node, "WHO"))
+		return nil
+	})
+	if err != nil || len(masters) != 2 || masters[0] == masters[1] {
+		t.Fatalf("ForEachMaster: got %v, %v", masters, err)
+	}
+}
+
+func TestClusterClient_MasterDown(t *testing.T) {
+	fc, nodes := newFakeCluster(t, "a", "b")
+	c := newFakeClusterClient(t, nodes...)
+	ctx := context.Background()
+
+	if got := whoami(t, c, "GET", "bar"); got != "a" {
+		t.Fatalf("expected a, got %q", got)
+	}
+
+	// a fails over to b: it stops accepting connections, so no MOVED
+	// redirection ever comes back.
+	nodes[0].close()
+	fc.mu.Lock()
+	for s := range fc.owner {
+		fc.owner[s] = nodes[1]
+	}
+	fc.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		reply, err := c.Do(ctx, "GET", "bar")
+		if err == nil && reply == "b" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the slots of a to move to b, got %v, %v", reply, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFirstKey(t *testing.T) {
+	for _, tc := range []struct {
+		args []any
+		key  string
+	}{
+		{[]any{"GET", "k"}, "k"},
+		{[]any{"EVALSHA", "sha", 1, "k"}, "k"},
+		{[]any{"EVALSHA", "sha", 0}, ""},
+		{[]any{"ZUNION", 2, "k", "z"}, "k"},
+		{[]any{"XREADGROUP", "GROUP", "g", "c", "STREAMS", "k", ">"}, "k"},
+		{[]any{"XGROUP", "CREATE", "k", "g", "$", "MKSTREAM"}, "k"},
+		{[]any{"xinfo", "STREAM", "k"}, "k"},
+		{[]any{"OBJECT", "ENCODING", "k"}, "k"},
+		{[]any{"MEMORY", "USAGE", "k"}, "k"},
+		{[]any{"MEMORY", "STATS"}, ""},
+		{[]any{"SCAN", "0"}, ""},
+	} {
+		if key, _ := redis.FirstKey(tc.args...); key != tc.key {
+			t.Errorf("FirstKey(%v) = %q, want %q", tc.args, key, tc.key)
+		}
+	}
+}
+
+func TestClusterClient_Moved(t *testing.T) {
+	fc, nodes := newFakeCluster(t, "a", "b")
+	c := newFakeClusterClient(t, nodes[0])
+
+	if got := whoami(t, c, "GET", "foo"); got != "b" {
+		t.Fatalf("expected b, got %q", got)
+	}
+	fc.move(redis.HashSlot("foo"), nodes[0])
+	if got := whoami(t, c, "GET", "foo"); got != "a" {
+		t.Fatalf("after MOVED: expected a, got %q", got)
+	}
+}
+
+func TestClusterClient_Ask(t *testing.T) {
+	fc, nodes := newFakeCluster(t, "a", "b")
+	c := newFakeClusterClient(t, nodes[0])
+
+	slot := redis.HashSlot("bar")
+	fc.mu.Lock()
+	fc.ask[slot] = nodes[1]
+	fc.mu.Unlock()
+
+	if got := whoami(t, c, "GET", "bar"); got != "b" {
+		t.Fatalf("after ASK: expected b, got %q", got)
+	}
+	// ASK does not change the slot owner.
+	fc.mu.Lock()
+	delete(fc.ask, slot)
+	fc.mu.Unlock()
+	if got := whoami(t, c, "GET", "bar"); got != "a" {
+		t.Fatalf("expected a, got %q", got)
+	}
+}
+
+func TestClusterClient_ScriptBroadcast(t *testing.T) {
+	fc, nodes := newFakeCluster(t, "a", "b")
+	c := newFakeClusterClient(t, nodes[0])
+	ctx := context.Background()
+
+	if _, err := c.ScriptLoad(ctx, "return 1"); err != nil {
+		t.Fatalf("ScriptLoad: %v", err)
+	}
+	fc.mu.Lock()
+	loaded := nodes[0].scripts == 1 && nodes[1].scripts == 1
+	nodes[0].hasSha = true
+	fc.mu.Unlock()
+	if !loaded {
+		t.Fatal("expected SCRIPT LOAD on every master")
+	}
+
+	ok, err := c.ScriptExists(ctx, "sha")
+	if err != nil || ok {
+		t.Fatalf("ScriptExists with one master missing: got %v, %v", ok, err)
+	}
+}
+
+func TestClusterClient_Pipeline(t *testing.T) {
+	_, nodes := newFakeCluster(t, "a", "b")
+	c := newFakeClusterClient(t, nodes[0])
+
+	cmds, err := c.Pipelined(context.Background(), func(p *redis.Pipeline) error {
+		p.Ping()
+		p.Get("{foo}:1")
+		p.Get("{foo}:2")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Pipelined: %v", err)
+	}
+	for _, cmd := range cmds[1:] {
+		if v, _ := cmd.Text(); v != "b" {
+			t.Fatalf("expected b, got %q", v)
+		}
+	}
+}
+
+func TestClusterClient_Scan(t *testing.T) {
+	_, nodes := newFakeCluster(t, "a", "b", "c")
+	c := newFakeClusterClient(t, nodes[0])
+	ctx := context.Background()
+
+	// Every page must come from the node of the first one.
+	for range 10 {
+		var keys []string
+		it := c.Scan(ctx, "", 0, "")
+		for it.Next() {
+			keys = append(keys, it.Val())
+		}
+		if err := it.Err(); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		name := keys[0][:1]
+		if want := []string{name + "0", name + "1", name + "2"}; !slices.Equal(keys, want) {
+			t.Fatalf("expected %v, got %v", want, keys)
+		}
+	}
+}
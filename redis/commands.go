@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -157,20 +158,7 @@ func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, er
 	if err != nil {
 		return nil, err
 	}
-	arr, ok := reply.([]any)
-	if !ok {
-		return nil, fmt.Errorf("redis: unexpected type %T from HGETALL", reply)
-	}
-	if len(arr)%2 != 0 {
-		return nil, fmt.Errorf("redis: HGETALL returned odd number of elements (%d)", len(arr))
-	}
-	m := make(map[string]string, len(arr)/2)
-	for i := 0; i < len(arr); i += 2 {
-		k, _ := arr[i].(string)
-		v, _ := arr[i+1].(string)
-		m[k] = v
-	}
-	return m, nil
+	return stringMap(reply, "HGETALL")
 }
 
 // HDel deletes one or more hash fields.
@@ -293,15 +281,7 @@ func (c *Client) LRange(ctx context.Context, key string, start, stop int64) ([]s
 	if err != nil {
 		return nil, err
 	}
-	arr, ok := reply.([]any)
-	if !ok {
-		return nil, fmt.Errorf("redis: unexpected type %T from LRANGE", reply)
-	}
-	result := make([]string, len(arr))
-	for i, v := range arr {
-		result[i], _ = v.(string)
-	}
-	return result, nil
+	return stringSlice(reply, "LRANGE")
 }
 
 // --- Set commands ---
@@ -330,15 +310,7 @@ func (c *Client) SMembers(ctx context.Context, key string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	arr, ok := reply.([]any)
-	if !ok {
-		return nil, fmt.Errorf("redis: unexpected type %T from SMEMBERS", reply)
-	}
-	result := make([]string, len(arr))
-	for i, v := range arr {
-		result[i], _ = v.(string)
-	}
-	return result, nil
+	return stringSlice(reply, "SMEMBERS")
 }
 
 // SRem removes members from a set and returns the number removed.
@@ -384,3 +356,507 @@ func (c *Client) SCard(ctx context.Context, key string) (int64, error) {
 	}
 	return n, nil
 }
+
+// --- Sorted set commands ---
+
+// Z is a sorted set member with its score.
+type Z struct {
+	Member string
+	Score  float64
+}
+
+// ZWithKey is a sorted set member popped by BZPOPMIN/BZPOPMAX, with the key it came from.
+type ZWithKey struct {
+	Z
+	Key string
+}
+
+// ZAddArgs holds the optional flags of ZADD.
+type ZAddArgs struct {
+	NX bool // only add new members
+	XX bool // only update existing members
+	GT bool // only update when the new score is greater
+	LT bool // only update when the new score is less
+	CH bool // count changed members, not only added ones
+}
+
+func (a ZAddArgs) appendTo(args []any) []any {
+	if a.NX {
+		args = append(args, "NX")
+	}
+	if a.XX {
+		args = append(args, "XX")
+	}
+	if a.GT {
+		args = append(args, "GT")
+	}
+	if a.LT {
+		args = append(args, "LT")
+	}
+	if a.CH {
+		args = append(args, "CH")
+	}
+	return args
+}
+
+// ZAdd adds members to a sorted set and returns the number of new members added.
+func (c *Client) ZAdd(ctx context.Context, key string, members ...Z) (int64, error) {
+	return c.ZAddArgs(ctx, key, ZAddArgs{}, members...)
+}
+
+// ZAddArgs adds members to a sorted set with the given flags. It returns the
+// number of members added, or changed when CH is set.
+func (c *Client) ZAddArgs(ctx context.Context, key string, a ZAddArgs, members ...Z) (int64, error) {
+	args := make([]any, 0, 7+2*len(members))
+	args = append(args, "ZADD", key)
+	args = a.appendTo(args)
+	for _, m := range members {
+		args = append(args, m.Score, m.Member)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected type %T from ZADD", reply)
+	}
+	return n, nil
+}
+
+// ZAddIncr increments the score of member like ZINCRBY, honouring the ZADD
+// flags, and returns the new score. ok is false if the flags prevented the update.
+func (c *Client) ZAddIncr(ctx context.Context, key string, a ZAddArgs, member Z) (score float64, ok bool, err error) {
+	args := make([]any, 0, 10)
+	args = append(args, "ZADD", key)
+	args = a.appendTo(args)
+	args = append(args, "INCR", member.Score, member.Member)
+	reply, err := c.Do(ctx, args...)
+	if err != nil || reply == nil {
+		return 0, false, err
+	}
+	score, err = parseScore(reply, "ZADD")
+	return score, err == nil, err
+}
+
+// ZIncrBy increments the score of member by incr and returns the new score.
+func (c *Client) ZIncrBy(ctx context.Context, key string, incr float64, member string) (float64, error) {
+	reply, err := c.Do(ctx, "ZINCRBY", key, incr, member)
+	if err != nil {
+		return 0, err
+	}
+	return parseScore(reply, "ZINCRBY")
+}
+
+// ZScore returns the score of member. ok is false if the member does not exist.
+func (c *Client) ZScore(ctx context.Context, key, member string) (score float64, ok bool, err error) {
+	reply, err := c.Do(ctx, "ZSCORE", key, member)
+	if err != nil || reply == nil {
+		return 0, false, err
+	}
+	score, err = parseScore(reply, "ZSCORE")
+	return score, err == nil, err
+}
+
+// ZMScore returns the scores of members. Missing members have a nil entry.
+func (c *Client) ZMScore(ctx context.Context, key string, members ...string) ([]*float64, error) {
+	reply, err := c.Do(ctx, keyArgs("ZMSCORE", members, key)...)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected type %T from ZMSCORE", reply)
+	}
+	result := make([]*float64, len(arr))
+	for i, v := range arr {
+		if v == nil {
+			continue
+		}
+		score, err := parseScore(v, "ZMSCORE")
+		if err != nil {
+			return nil, err
+		}
+		result[i] = &score
+	}
+	return result, nil
+}
+
+// ZRank returns the rank of member, ordered from low to high scores.
+// ok is false if the member does not exist.
+func (c *Client) ZRank(ctx context.Context, key, member string) (rank int64, ok bool, err error) {
+	return c.zrank(ctx, "ZRANK", key, member)
+}
+
+// ZRevRank returns the rank of member, ordered from high to low scores.
+// ok is false if the member does not exist.
+func (c *Client) ZRevRank(ctx context.Context, key, member string) (rank int64, ok bool, err error) {
+	return c.zrank(ctx, "ZREVRANK", key, member)
+}
+
+func (c *Client) zrank(ctx context.Context, name, key, member string) (int64, bool, error) {
+	reply, err := c.Do(ctx, name, key, member)
+	if err != nil || reply == nil {
+		return 0, false, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, false, fmt.Errorf("redis: unexpected type %T from %s", reply, name)
+	}
+	return n, true, nil
+}
+
+// ZRangeArgs describes a ZRANGE query. Start and Stop are ranks by default,
+// scores with ByScore ("-inf", "(1.5") or lexicographic bounds with ByLex ("[a", "+").
+type ZRangeArgs struct {
+	Start, Stop any
+	ByScore     bool
+	ByLex       bool
+	Rev         bool
+	// Offset and Count apply a LIMIT when Count is non-zero (ByScore or ByLex only).
+	Offset, Count int64
+}
+
+func (a ZRangeArgs) args(key string, withScores bool) []any {
+	args := make([]any, 0, 10)
+	args = append(args, "ZRANGE", key, a.Start, a.Stop)
+	if a.ByScore {
+		args = append(args, "BYSCORE")
+	} else if a.ByLex {
+		args = append(args, "BYLEX")
+	}
+	if a.Rev {
+		args = append(args, "REV")
+	}
+	if a.Count != 0 {
+		args = append(args, "LIMIT", a.Offset, a.Count)
+	}
+	if withScores {
+		args = append(args, "WITHSCORES")
+	}
+	return args
+}
+
+// ZRange returns members by rank, from low to high scores.
+func (c *Client) ZRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return c.ZRangeArgs(ctx, key, ZRangeArgs{Start: start, Stop: stop})
+}
+
+// ZRangeWithScores returns members and scores by rank, from low to high scores.
+func (c *Client) ZRangeWithScores(ctx context.Context, key string, start, stop int64) ([]Z, error) {
+	return c.ZRangeArgsWithScores(ctx, key, ZRangeArgs{Start: start, Stop: stop})
+}
+
+// ZRangeArgs returns members matching a ZRANGE query.
+func (c *Client) ZRangeArgs(ctx context.Context, key string, a ZRangeArgs) ([]string, error) {
+	reply, err := c.Do(ctx, a.args(key, false)...)
+	if err != nil {
+		return nil, err
+	}
+	return stringSlice(reply, "ZRANGE")
+}
+
+// ZRangeArgsWithScores returns members and scores matching a ZRANGE query.
+func (c *Client) ZRangeArgsWithScores(ctx context.Context, key string, a ZRangeArgs) ([]Z, error) {
+	reply, err := c.Do(ctx, a.args(key, true)...)
+	if err != nil {
+		return nil, err
+	}
+	return zSlice(reply, "ZRANGE")
+}
+
+// ZRem removes members from a sorted set and returns the number removed.
+func (c *Client) ZRem(ctx context.Context, key string, members ...string) (int64, error) {
+	return c.int64Cmd(ctx, keyArgs("ZREM", members, key)...)
+}
+
+// ZRemRangeByRank removes members within the given ranks and returns the number removed.
+func (c *Client) ZRemRangeByRank(ctx context.Context, key string, start, stop int64) (int64, error) {
+	return c.int64Cmd(ctx, "ZREMRANGEBYRANK", key, start, stop)
+}
+
+// ZRemRangeByScore removes members with scores within [min, max] and returns the number removed.
+// Bounds use ZRANGE syntax ("-inf", "(1.5").
+func (c *Client) ZRemRangeByScore(ctx context.Context, key, min, max string) (int64, error) {
+	return c.int64Cmd(ctx, "ZREMRANGEBYSCORE", key, min, max)
+}
+
+// ZRemRangeByLex removes members within a lexicographic range and returns the number removed.
+func (c *Client) ZRemRangeByLex(ctx context.Context, key, min, max string) (int64, error) {
+	return c.int64Cmd(ctx, "ZREMRANGEBYLEX", key, min, max)
+}
+
+// ZCard returns the number of members in a sorted set.
+func (c *Client) ZCard(ctx context.Context, key string) (int64, error) {
+	return c.int64Cmd(ctx, "ZCARD", key)
+}
+
+// ZCount returns the number of members with scores within [min, max].
+func (c *Client) ZCount(ctx context.Context, key, min, max string) (int64, error) {
+	return c.int64Cmd(ctx, "ZCOUNT", key, min, max)
+}
+
+// ZPopMin removes and returns up to count members with the lowest scores.
+func (c *Client) ZPopMin(ctx context.Context, key string, count int64) ([]Z, error) {
+	return c.zpop(ctx, "ZPOPMIN", key, count)
+}
+
+// ZPopMax removes and returns up to count members with the highest scores.
+func (c *Client) ZPopMax(ctx context.Context, key string, count int64) ([]Z, error) {
+	return c.zpop(ctx, "ZPOPMAX", key, count)
+}
+
+func (c *Client) zpop(ctx context.Context, name, key string, count int64) ([]Z, error) {
+	args := []any{name, key}
+	if count > 1 {
+		args = append(args, count)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return zSlice(reply, name)
+}
+
+// BZPopMin blocks until a member can be popped from the first non-empty key,
+// or timeout elapses (0 blocks indefinitely). It returns nil on timeout.
+func (c *Client) BZPopMin(ctx context.Context, timeout time.Duration, keys ...string) (*ZWithKey, error) {
+	return c.bzpop(ctx, "BZPOPMIN", timeout, keys)
+}
+
+// BZPopMax is like BZPopMin but pops the member with the highest score.
+func (c *Client) BZPopMax(ctx context.Context, timeout time.Duration, keys ...string) (*ZWithKey, error) {
+	return c.bzpop(ctx, "BZPOPMAX", timeout, keys)
+}
+
+func (c *Client) bzpop(ctx context.Context, name string, timeout time.Duration, keys []string) (*ZWithKey, error) {
+	args := keyArgs(name, keys)
+	args = append(args, timeout.Seconds())
+	reply, err := c.Do(withBlock(ctx, timeout), args...)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	arr, ok := reply.([]any)
+	if !ok || len(arr) != 3 {
+		return nil, fmt.Errorf("redis: unexpected reply %T from %s", reply, name)
+	}
+	key, _ := arr[0].(string)
+	member, _ := arr[1].(string)
+	score, err := parseScore(arr[2], name)
+	if err != nil {
+		return nil, err
+	}
+	return &ZWithKey{Z: Z{Member: member, Score: score}, Key: key}, nil
+}
+
+// ZStore describes the input of ZUNION/ZINTER and their STORE variants.
+type ZStore struct {
+	Keys    []string
+	Weights []float64
+	// Aggregate is "SUM" (default), "MIN" or "MAX".
+	Aggregate string
+}
+
+func (s ZStore) args(name, dest string, withScores bool) []any {
+	args := make([]any, 0, 6+len(s.Keys)+len(s.Weights))
+	args = append(args, name)
+	if dest != "" {
+		args = append(args, dest)
+	}
+	args = append(args, len(s.Keys))
+	for _, k := range s.Keys {
+		args = append(args, k)
+	}
+	if len(s.Weights) > 0 {
+		args = append(args, "WEIGHTS")
+		for _, w := range s.Weights {
+			args = append(args, w)
+		}
+	}
+	if s.Aggregate != "" {
+		args = append(args, "AGGREGATE", s.Aggregate)
+	}
+	if withScores {
+		args = append(args, "WITHSCORES")
+	}
+	return args
+}
+
+// ZUnion returns the union of the sorted sets.
+func (c *Client) ZUnion(ctx context.Context, store ZStore) ([]string, error) {
+	return c.zsetOp(ctx, store.args("ZUNION", "", false)...)
+}
+
+// ZUnionWithScores returns the union of the sorted sets with aggregated scores.
+func (c *Client) ZUnionWithScores(ctx context.Context, store ZStore) ([]Z, error) {
+	return c.zsetOpWithScores(ctx, store.args("ZUNION", "", true)...)
+}
+
+// ZUnionStore stores the union of the sorted sets in dest and returns its size.
+func (c *Client) ZUnionStore(ctx context.Context, dest string, store ZStore) (int64, error) {
+	return c.int64Cmd(ctx, store.args("ZUNIONSTORE", dest, false)...)
+}
+
+// ZInter returns the intersection of the sorted sets.
+func (c *Client) ZInter(ctx context.Context, store ZStore) ([]string, error) {
+	return c.zsetOp(ctx, store.args("ZINTER", "", false)...)
+}
+
+// ZInterWithScores returns the intersection of the sorted sets with aggregated scores.
+func (c *Client) ZInterWithScores(ctx context.Context, store ZStore) ([]Z, error) {
+	return c.zsetOpWithScores(ctx, store.args("ZINTER", "", true)...)
+}
+
+// ZInterStore stores the intersection of the sorted sets in dest and returns its size.
+func (c *Client) ZInterStore(ctx context.Context, dest string, store ZStore) (int64, error) {
+	return c.int64Cmd(ctx, store.args("ZINTERSTORE", dest, false)...)
+}
+
+// ZDiff returns the members of the first sorted set not present in the others.
+func (c *Client) ZDiff(ctx context.Context, keys ...string) ([]string, error) {
+	return c.zsetOp(ctx, ZStore{Keys: keys}.args("ZDIFF", "", false)...)
+}
+
+// ZDiffWithScores is like ZDiff but also returns scores.
+func (c *Client) ZDiffWithScores(ctx context.Context, keys ...string) ([]Z, error) {
+	return c.zsetOpWithScores(ctx, ZStore{Keys: keys}.args("ZDIFF", "", true)...)
+}
+
+// ZDiffStore stores the difference of the sorted sets in dest and returns its size.
+func (c *Client) ZDiffStore(ctx context.Context, dest string, keys ...string) (int64, error) {
+	return c.int64Cmd(ctx, ZStore{Keys: keys}.args("ZDIFFSTORE", dest, false)...)
+}
+
+func (c *Client) zsetOp(ctx context.Context, args ...any) ([]string, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return stringSlice(reply, args[0].(string))
+}
+
+func (c *Client) zsetOpWithScores(ctx context.Context, args ...any) ([]Z, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return zSlice(reply, args[0].(string))
+}
+
+// --- Reply helpers ---
+
+// stringSlice converts an array or RESP3 set reply to a string slice.
+func stringSlice(reply any, name string) ([]string, error) {
+	var arr []any
+	switch v := reply.(type) {
+	case []any:
+		arr = v
+	case Set:
+		arr = v
+	default:
+		return nil, fmt.Errorf("redis: unexpected type %T from %s", reply, name)
+	}
+	result := make([]string, len(arr))
+	for i, v := range arr {
+		result[i], _ = v.(string)
+	}
+	return result, nil
+}
+
+// stringMap converts a flat field/value array or RESP3 map reply to a map.
+func stringMap(reply any, name string) (map[string]string, error) {
+	switch v := reply.(type) {
+	case map[any]any:
+		m := make(map[string]string, len(v))
+		for k, val := range v {
+			ks, _ := k.(string)
+			vs, _ := val.(string)
+			m[ks] = vs
+		}
+		return m, nil
+	case []any:
+		if len(v)%2 != 0 {
+			return nil, fmt.Errorf("redis: %s returned odd number of elements (%d)", name, len(v))
+		}
+		m := make(map[string]string, len(v)/2)
+		for i := 0; i < len(v); i += 2 {
+			k, _ := v[i].(string)
+			val, _ := v[i+1].(string)
+			m[k] = val
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected type %T from %s", reply, name)
+	}
+}
+
+// int64Cmd executes a command with an integer reply.
+func (c *Client) int64Cmd(ctx context.Context, args ...any) (int64, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected type %T from %s", reply, args[0])
+	}
+	return n, nil
+}
+
+// parseScore converts a score reply: a bulk string in RESP2, a double in RESP3.
+func parseScore(reply any, name string) (float64, error) {
+	switch v := reply.(type) {
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("redis: invalid score %q from %s", v, name)
+		}
+		return f, nil
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	default:
+		return 0, fmt.Errorf("redis: unexpected type %T from %s", reply, name)
+	}
+}
+
+// zSlice converts a WITHSCORES reply to members and scores. RESP2 replies are
+// flat member/score arrays; RESP3 replies are arrays of [member, score] pairs.
+func zSlice(reply any, name string) ([]Z, error) {
+	arr, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected type %T from %s", reply, name)
+	}
+	if len(arr) > 0 {
+		if _, nested := arr[0].([]any); nested {
+			zs := make([]Z, len(arr))
+			for i, v := range arr {
+				pair, ok := v.([]any)
+				if !ok || len(pair) != 2 {
+					return nil, fmt.Errorf("redis: unexpected member %v from %s", v, name)
+				}
+				score, err := parseScore(pair[1], name)
+				if err != nil {
+					return nil, err
+				}
+				zs[i].Member, _ = pair[0].(string)
+				zs[i].Score = score
+			}
+			return zs, nil
+		}
+	}
+	if len(arr)%2 != 0 {
+		return nil, fmt.Errorf("redis: %s returned odd number of elements (%d)", name, len(arr))
+	}
+	zs := make([]Z, len(arr)/2)
+	for i := range zs {
+		score, err := parseScore(arr[2*i+1], name)
+		if err != nil {
+			return nil, err
+		}
+		zs[i].Member, _ = arr[2*i].(string)
+		zs[i].Score = score
+	}
+	return zs, nil
+}
@@ -0,0 +1,241 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Default stream consumer settings.
+const (
+	defaultConsumerCount         = 10
+	defaultConsumerBlock         = 5 * time.Second
+	defaultConsumerClaimInterval = 30 * time.Second
+	defaultConsumerClaimMinIdle  = time.Minute
+	consumerRetryBackoff         = time.Second
+)
+
+// StreamHandler processes one stream entry. Returning nil acknowledges it;
+// an error leaves it pending so that it is redelivered by a later autoclaim.
+type StreamHandler func(ctx context.Context, msg XMessage) error
+
+// ConsumerOptions configures a StreamConsumer.
+type ConsumerOptions struct {
+	Stream   string
+	Group    string
+	Consumer string
+	Handler  StreamHandler
+
+	// Count is the maximum number of entries read per XREADGROUP.
+	// Default: 10.
+	Count int64
+
+	// Block is how long each XREADGROUP waits for new entries. It also bounds
+	// how long Run takes to notice a cancelled context.
+	// Default: 5s.
+	Block time.Duration
+
+	// ClaimInterval is how often pending entries of other (possibly dead)
+	// consumers are taken over with XAUTOCLAIM.
+	// Default: 30s. Set to -1 to disable.
+	ClaimInterval time.Duration
+
+	// ClaimMinIdle is how long an entry must stay pending before it is claimed.
+	// Default: 1m.
+	ClaimMinIdle time.Duration
+
+	// ErrorHandler, if set, receives handler and command errors. Run keeps
+	// going after reporting them.
+	ErrorHandler func(err error)
+}
+
+func (o *ConsumerOptions) count() int64 {
+	if o.Count > 0 {
+		return o.Count
+	}
+	return defaultConsumerCount
+}
+
+func (o *ConsumerOptions) block() time.Duration {
+	if o.Block > 0 {
+		return o.Block
+	}
+	return defaultConsumerBlock
+}
+
+func (o *ConsumerOptions) claimInterval() time.Duration {
+	if o.ClaimInterval > 0 {
+		return o.ClaimInterval
+	}
+	if o.ClaimInterval < 0 {
+		return 0 // disabled
+	}
+	return defaultConsumerClaimInterval
+}
+
+func (o *ConsumerOptions) claimMinIdle() time.Duration {
+	if o.ClaimMinIdle > 0 {
+		return o.ClaimMinIdle
+	}
+	return defaultConsumerClaimMinIdle
+}
+
+// StreamConsumer reads a stream as a member of a consumer group, dispatches
+// entries to a handler and acknowledges those handled successfully.
+type StreamConsumer struct {
+	c    *Client
+	opts ConsumerOptions
+}
+
+// NewStreamConsumer returns a consumer for the given options. Call Run to start it.
+func (c *Client) NewStreamConsumer(opts *ConsumerOptions) *StreamConsumer {
+	return &StreamConsumer{c: c, opts: *opts}
+}
+
+// Run creates the consumer group if needed (with MKSTREAM), then processes
+// entries until ctx is done, returning ctx.Err(). It first re-reads this
+// consumer's own pending entries left over from a previous run, then blocks
+// for new ones, autoclaiming stale entries every ClaimInterval.
+func (sc *StreamConsumer) Run(ctx context.Context) error {
+	o := &sc.opts
+	if o.Stream == "" || o.Group == "" || o.Consumer == "" || o.Handler == nil {
+		return fmt.Errorf("redis: stream consumer needs Stream, Group, Consumer and Handler")
+	}
+	if err := sc.createGroup(ctx); err != nil {
+		return err
+	}
+
+	cursor := "0" // own pending entries first, then ">"
+	interval := o.claimInterval()
+	nextClaim := time.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if interval > 0 && !time.Now().Before(nextClaim) {
+			sc.autoclaim(ctx)
+			nextClaim = time.Now().Add(interval)
+		}
+
+		var block time.Duration
+		if cursor == ">" {
+			block = o.block()
+		}
+		streams, err := sc.c.XReadGroup(ctx, XReadGroupArgs{
+			Group:    o.Group,
+			Consumer: o.Consumer,
+			Streams:  []string{o.Stream},
+			IDs:      []string{cursor},
+			Count:    o.count(),
+			Block:    block,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			sc.report(err)
+			if errors.Is(err, ErrNoGroup) {
+				// The stream or group was deleted; recreate it.
+				if err := sc.createGroup(ctx); err != nil {
+					sc.report(err)
+				}
+			}
+			if !sleepCtx(ctx, consumerRetryBackoff) {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		var msgs []XMessage
+		for _, s := range streams {
+			msgs = append(msgs, s.Messages...)
+		}
+		if cursor != ">" {
+			if len(msgs) == 0 {
+				cursor = ">"
+				continue
+			}
+			cursor = msgs[len(msgs)-1].ID
+		}
+		sc.handle(ctx, msgs)
+	}
+}
+
+func (sc *StreamConsumer) createGroup(ctx context.Context) error {
+	err := sc.c.XGroupCreateMkStream(ctx, sc.opts.Stream, sc.opts.Group, "$")
+	if err != nil && !errors.Is(err, ErrBusyGroup) {
+		return err
+	}
+	return nil
+}
+
+// autoclaim takes over entries pending longer than ClaimMinIdle and handles them.
+func (sc *StreamConsumer) autoclaim(ctx context.Context) {
+	o := &sc.opts
+	start := "0-0"
+	for {
+		msgs, next, err := sc.c.XAutoClaim(ctx, XAutoClaimArgs{
+			Stream:   o.Stream,
+			Group:    o.Group,
+			Consumer: o.Consumer,
+			MinIdle:  o.claimMinIdle(),
+			Start:    start,
+			Count:    o.count(),
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				sc.report(err)
+			}
+			return
+		}
+		sc.handle(ctx, msgs)
+		if next == "" || next == "0-0" || ctx.Err() != nil {
+			return
+		}
+		start = next
+	}
+}
+
+// handle dispatches msgs to the handler and acknowledges the successful ones.
+// Entries deleted from the stream while pending (nil Values) are acknowledged
+// without calling the handler.
+func (sc *StreamConsumer) handle(ctx context.Context, msgs []XMessage) {
+	if len(msgs) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Values != nil {
+			if err := sc.opts.Handler(ctx, msg); err != nil {
+				sc.report(fmt.Errorf("redis: stream entry %s: %w", msg.ID, err))
+				continue
+			}
+		}
+		ids = append(ids, msg.ID)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if _, err := sc.c.XAck(ctx, sc.opts.Stream, sc.opts.Group, ids...); err != nil {
+		sc.report(err)
+	}
+}
+
+func (sc *StreamConsumer) report(err error) {
+	if sc.opts.ErrorHandler != nil {
+		sc.opts.ErrorHandler(err)
+	}
+}
+
+// sleepCtx waits for d and reports false if ctx ended first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
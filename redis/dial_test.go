@@ -0,0 +1,222 @@
+package redis_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+func TestClient_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	startFakeServer(t, ln, func([]string) string { return bulk("unix") })
+
+	c := redis.NewClient(&redis.Options{Network: "unix", Addr: path})
+	defer c.Close()
+	if got := whoami(t, c, "WHO"); got != "unix" {
+		t.Fatalf("expected unix, got %q", got)
+	}
+}
+
+func TestClient_Dialer(t *testing.T) {
+	s := nameServer(t, "pipe")
+
+	var dials atomic.Int32
+	c := redis.NewClient(&redis.Options{
+		Addr: "redis.invalid:6379", // never resolved
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if network != "tcp" || addr != "redis.invalid:6379" {
+				t.Errorf("Dialer got %s %s", network, addr)
+			}
+			dials.Add(1)
+			client, server := net.Pipe()
+			go s.serveConn(server)
+			return client, nil
+		},
+	})
+	defer c.Close()
+
+	for range 3 {
+		if got := whoami(t, c, "WHO"); got != "pipe" {
+			t.Fatalf("expected pipe, got %q", got)
+		}
+	}
+	if n := dials.Load(); n != 1 {
+		t.Fatalf("expected 1 dial, got %d", n)
+	}
+}
+
+func TestClient_DialerTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	s, sni := newFakeTLSServer(t, &tls.Config{Certificates: []tls.Certificate{pki.server}}, nil)
+
+	// The dialer reaches the server through another address; SNI and
+	// verification still use the configured host.
+	c := redis.NewClient(&redis.Options{
+		Addr:      "redis.test:6380",
+		TLSConfig: &tls.Config{RootCAs: pki.pool},
+		Dialer: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, s.addr())
+		},
+	})
+	defer c.Close()
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if got := sni(); len(got) != 1 || got[0] != "redis.test" {
+		t.Fatalf("expected SNI redis.test, got %q", got)
+	}
+}
+
+// recordServer replies +OK to every command and records them.
+func recordServer(t *testing.T) (*fakeServer, func() []string) {
+	var mu sync.Mutex
+	var cmds []string
+	s := newFakeServer(t, func(args []string) string {
+		mu.Lock()
+		cmds = append(cmds, strings.Join(args, " "))
+		mu.Unlock()
+		return "+OK\r\n"
+	})
+	return s, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), cmds...)
+	}
+}
+
+func TestClient_ConnectionSetup(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol int
+		want     []string
+	}{
+		{"RESP2", 2, []string{"AUTH app secret", "CLIENT SETNAME worker-1", "SELECT 2", "CLIENT TRACKING on"}},
+		{"RESP3", 3, []string{"HELLO 3 AUTH app secret SETNAME worker-1", "SELECT 2", "CLIENT TRACKING on"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, cmds := recordServer(t)
+			var remote string
+			c := redis.NewClient(&redis.Options{
+				Addr:       s.addr(),
+				Username:   "app",
+				Password:   "secret",
+				ClientName: "worker-1",
+				DB:         2,
+				Protocol:   tt.protocol,
+				OnConnect: func(ctx context.Context, cn *redis.Conn) error {
+					remote = cn.RemoteAddr()
+					_, err := cn.Do(ctx, "CLIENT", "TRACKING", "on")
+					return err
+				},
+			})
+			defer c.Close()
+
+			if err := c.Ping(context.Background()); err != nil {
+				t.Fatalf("Ping: %v", err)
+			}
+			if got := cmds(); !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+			if remote != s.addr() {
+				t.Fatalf("RemoteAddr: expected %s, got %s", s.addr(), remote)
+			}
+		})
+	}
+}
+
+func TestClient_DefaultUserAuth(t *testing.T) {
+	s, cmds := recordServer(t)
+	c := redis.NewClient(&redis.Options{Addr: s.addr(), Password: "secret", Protocol: 3})
+	defer c.Close()
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if got := cmds(); len(got) != 1 || got[0] != "HELLO 3 AUTH default secret" {
+		t.Fatalf("unexpected handshake %q", got)
+	}
+}
+
+func TestClient_OnConnectError(t *testing.T) {
+	s := nameServer(t, "a")
+	errInit := errors.New("init failed")
+	c := redis.NewClient(&redis.Options{
+		Addr: s.addr(),
+		OnConnect: func(context.Context, *redis.Conn) error {
+			return errInit
+		},
+	})
+	defer c.Close()
+
+	if err := c.Ping(context.Background()); !errors.Is(err, errInit) {
+		t.Fatalf("expected %v, got %v", errInit, err)
+	}
+	if st := c.PoolStats(); st.Active != 0 {
+		t.Fatalf("expected the connection to be discarded, got %+v", st)
+	}
+}
+
+func TestClient_CredentialsProvider(t *testing.T) {
+	s, cmds := recordServer(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	pass := "v1"
+	errRead := errors.New("secret unavailable")
+	var fail bool
+	c := redis.NewClient(&redis.Options{
+		Addr:     s.addr(),
+		Password: "ignored",
+		CredentialsProvider: func(context.Context) (string, string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if fail {
+				return "", "", errRead
+			}
+			return "app", pass, nil
+		},
+	})
+	defer c.Close()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	mu.Lock()
+	pass = "v2"
+	mu.Unlock()
+
+	// The idle connection keeps its session; a second one dials with the
+	// rotated secret.
+	err := c.Watch(ctx, func(*redis.Tx) error { return c.Ping(ctx) })
+	if err != nil {
+		t.Fatalf("Ping on a second connection: %v", err)
+	}
+	want := []string{"AUTH app v1", "AUTH app v2"}
+	if got := cmds(); !slices.Equal(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	err = c.Watch(ctx, func(*redis.Tx) error {
+		return c.Watch(ctx, func(*redis.Tx) error { return c.Ping(ctx) })
+	})
+	if !errors.Is(err, errRead) {
+		t.Fatalf("expected %v, got %v", errRead, err)
+	}
+}
@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"errors"
+	"strings"
+)
+
+// Sentinel errors matched by RedisError via errors.Is, keyed on the error code
+// (the first word of the reply), e.g. errors.Is(err, redis.ErrNoScript).
+var (
+	ErrNoScript    = errors.New("redis: NOSCRIPT")
+	ErrWrongType   = errors.New("redis: WRONGTYPE")
+	ErrBusy        = errors.New("redis: BUSY")
+	ErrMoved       = errors.New("redis: MOVED")
+	ErrAsk         = errors.New("redis: ASK")
+	ErrLoading     = errors.New("redis: LOADING")
+	ErrReadOnly    = errors.New("redis: READONLY")
+	ErrTryAgain    = errors.New("redis: TRYAGAIN")
+	ErrClusterDown = errors.New("redis: CLUSTERDOWN")
+	ErrMasterDown  = errors.New("redis: MASTERDOWN")
+	ErrNoAuth      = errors.New("redis: NOAUTH")
+	ErrWrongPass   = errors.New("redis: WRONGPASS")
+	ErrNoPerm      = errors.New("redis: NOPERM")
+	ErrExecAbort   = errors.New("redis: EXECABORT")
+	ErrOOM         = errors.New("redis: OOM")
+	ErrBusyGroup   = errors.New("redis: BUSYGROUP")
+	ErrNoGroup     = errors.New("redis: NOGROUP")
+)
+
+// ErrTxFailed is returned when EXEC aborts a transaction because a watched key changed.
+var ErrTxFailed = errors.New("redis: transaction failed")
+
+// ErrPoolTimeout is returned when no connection became available within
+// Options.PoolTimeout.
+var ErrPoolTimeout = errors.New("redis: connection pool timeout")
+
+// ErrCircuitOpen is returned without contacting the server while the circuit
+// breaker of Options.CircuitBreaker is open.
+var ErrCircuitOpen = errors.New("redis: circuit breaker is open")
+
+var errorCodes = map[string]error{
+	"NOSCRIPT":    ErrNoScript,
+	"WRONGTYPE":   ErrWrongType,
+	"BUSY":        ErrBusy,
+	"MOVED":       ErrMoved,
+	"ASK":         ErrAsk,
+	"LOADING":     ErrLoading,
+	"READONLY":    ErrReadOnly,
+	"TRYAGAIN":    ErrTryAgain,
+	"CLUSTERDOWN": ErrClusterDown,
+	"MASTERDOWN":  ErrMasterDown,
+	"NOAUTH":      ErrNoAuth,
+	"WRONGPASS":   ErrWrongPass,
+	"NOPERM":      ErrNoPerm,
+	"EXECABORT":   ErrExecAbort,
+	"OOM":         ErrOOM,
+	"BUSYGROUP":   ErrBusyGroup,
+	"NOGROUP":     ErrNoGroup,
+}
+
+// RedisError represents an error reply from Redis, e.g. "WRONGTYPE Operation
+// against a key holding the wrong kind of value".
+type RedisError string
+
+func (e RedisError) Error() string { return string(e) }
+
+// Code returns the error code, i.e. the first word of the reply ("ERR", "NOSCRIPT", ...).
+func (e RedisError) Code() string {
+	code, _, _ := strings.Cut(string(e), " ")
+	return code
+}
+
+// Message returns the reply text following the error code.
+func (e RedisError) Message() string {
+	_, msg, _ := strings.Cut(string(e), " ")
+	return msg
+}
+
+// Is reports whether target is the sentinel error for this reply's code.
+func (e RedisError) Is(target error) bool {
+	sentinel, ok := errorCodes[e.Code()]
+	return ok && sentinel == target
+}
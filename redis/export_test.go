@@ -0,0 +1,16 @@
+package redis
+
+// CloseConn closes the current PubSub connection to simulate a network failure.
+func (ps *PubSub) CloseConn() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.cn != nil {
+		ps.cn.nc.Close()
+	}
+}
+
+// FirstKey returns the key a cluster client routes args by.
+func FirstKey(args ...any) (string, bool) { return firstKey(args) }
+
+// Reap runs one pass of the background reaper.
+func (c *Client) Reap() { c.reap() }
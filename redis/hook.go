@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"context"
+	"net"
+)
+
+// DialHook opens a network connection to addr.
+type DialHook func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// ProcessHook runs one command and returns its reply.
+type ProcessHook func(ctx context.Context, args []any) (any, error)
+
+// ProcessPipelineHook runs a batch of commands from a Pipeline or a
+// TxPipeline. Replies are stored on each Cmd; the returned error is an I/O
+// error or the first command error.
+type ProcessPipelineHook func(ctx context.Context, cmds []*Cmd) error
+
+// Hook intercepts dials, commands and pipelines. Each method is given the
+// next step of the chain and returns the function to run in its place; a
+// hook that is not interested in a step returns next unchanged.
+//
+// Hooks see the command as issued: for Sentinel and Cluster clients they
+// run once, before the command is routed to a node, and redirections are
+// not visible to them. DialHook runs for every connection the client
+// opens, including those to replicas and cluster nodes.
+type Hook interface {
+	DialHook(next DialHook) DialHook
+	ProcessHook(next ProcessHook) ProcessHook
+	ProcessPipelineHook(next ProcessPipelineHook) ProcessPipelineHook
+}
+
+// AddHook appends h to the hooks of c. The first hook added is the
+// outermost one. AddHook is safe to call while the client is in use;
+// commands already running are not affected.
+func (c *Client) AddHook(h Hook) {
+	root := c.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	var hooks []Hook
+	if old := root.hooks.Load(); old != nil {
+		hooks = append(hooks, *old...)
+	}
+	hooks = append(hooks, h)
+	root.hooks.Store(&hooks)
+}
+
+// root returns the client that owns the hooks: the client the user
+// created, for replica and cluster node clients.
+func (c *Client) root() *Client {
+	if c.parent != nil {
+		return c.parent
+	}
+	return c
+}
+
+func (c *Client) hookList() []Hook {
+	if hooks := c.root().hooks.Load(); hooks != nil {
+		return *hooks
+	}
+	return nil
+}
+
+func (c *Client) dialHook(base DialHook) DialHook {
+	hooks := c.hookList()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		base = hooks[i].DialHook(base)
+	}
+	return base
+}
+
+func (c *Client) processHook(base ProcessHook) ProcessHook {
+	hooks := c.hookList()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		base = hooks[i].ProcessHook(base)
+	}
+	return base
+}
+
+func (c *Client) processPipelineHook(base ProcessPipelineHook) ProcessPipelineHook {
+	hooks := c.hookList()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		base = hooks[i].ProcessPipelineHook(base)
+	}
+	return base
+}
@@ -0,0 +1,187 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+// traceHook records every dial, command and pipeline it sees.
+type traceHook struct {
+	name string
+	mu   *sync.Mutex
+	log  *[]string
+}
+
+func (h traceHook) record(format string, args ...any) {
+	h.mu.Lock()
+	*h.log = append(*h.log, h.name+":"+fmt.Sprintf(format, args...))
+	h.mu.Unlock()
+}
+
+func (h traceHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		h.record("dial %s", network)
+		return next(ctx, network, addr)
+	}
+}
+
+func (h traceHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, args []any) (any, error) {
+		h.record("before %v", args[0])
+		reply, err := next(ctx, args)
+		h.record("after %v", reply)
+		return reply, err
+	}
+}
+
+func (h traceHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []*redis.Cmd) error {
+		h.record("pipeline %d", len(cmds))
+		return next(ctx, cmds)
+	}
+}
+
+func newTraceHooks(names ...string) ([]redis.Hook, func() []string) {
+	var mu sync.Mutex
+	var log []string
+	hooks := make([]redis.Hook, len(names))
+	for i, name := range names {
+		hooks[i] = traceHook{name: name, mu: &mu, log: &log}
+	}
+	return hooks, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		out := append([]string(nil), log...)
+		log = log[:0]
+		return out
+	}
+}
+
+func TestClient_Hooks(t *testing.T) {
+	s := nameServer(t, "a")
+	c := redis.NewClient(&redis.Options{Addr: s.addr()})
+	defer c.Close()
+	ctx := context.Background()
+
+	hooks, trace := newTraceHooks("1", "2")
+	for _, h := range hooks {
+		c.AddHook(h)
+	}
+
+	if got := whoami(t, c, "WHO"); got != "a" {
+		t.Fatalf("expected a, got %q", got)
+	}
+	want := []string{
+		"1:before WHO", "2:before WHO",
+		"1:dial tcp", "2:dial tcp",
+		"2:after a", "1:after a",
+	}
+	if got := trace(); !slices.Equal(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	if _, err := c.Pipelined(ctx, func(p *redis.Pipeline) error {
+		p.Get("x")
+		p.Get("y")
+		return nil
+	}); err != nil {
+		t.Fatalf("Pipelined: %v", err)
+	}
+	if got := trace(); !slices.Equal(got, []string{"1:pipeline 2", "2:pipeline 2"}) {
+		t.Fatalf("unexpected pipeline trace %q", got)
+	}
+
+	// Typed helpers and Tx commands go through the hooks too.
+	err := c.Watch(ctx, func(tx *redis.Tx) error {
+		_, err := tx.Get(ctx, "k")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	want = []string{"1:before GET", "2:before GET", "2:after a", "1:after a"}
+	if got := trace(); !slices.Equal(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+// faultHook fails commands named cmd without sending them.
+type faultHook struct {
+	cmd string
+	err error
+}
+
+func (h faultHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h faultHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, args []any) (any, error) {
+		if args[0] == h.cmd {
+			return nil, h.err
+		}
+		return next(ctx, args)
+	}
+}
+
+func (h faultHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestClient_HookFaultInjection(t *testing.T) {
+	s := nameServer(t, "a")
+	c := redis.NewClient(&redis.Options{Addr: s.addr()})
+	defer c.Close()
+
+	errFault := errors.New("injected")
+	c.AddHook(faultHook{cmd: "GET", err: errFault})
+
+	if _, err := c.Get(context.Background(), "k"); !errors.Is(err, errFault) {
+		t.Fatalf("expected %v, got %v", errFault, err)
+	}
+	if got := whoami(t, c, "WHO"); got != "a" {
+		t.Fatalf("expected a, got %q", got)
+	}
+	if st := c.PoolStats(); st.Active != 1 {
+		t.Fatalf("expected one connection, got %+v", st)
+	}
+}
+
+func TestClusterClient_Hooks(t *testing.T) {
+	fc, nodes := newFakeCluster(t, "a", "b")
+	c := newFakeClusterClient(t, nodes[0])
+
+	hooks, trace := newTraceHooks("h")
+	c.AddHook(hooks[0])
+
+	fc.move(redis.HashSlot("foo"), nodes[0])
+	if got := whoami(t, c, "GET", "foo"); got != "a" {
+		t.Fatalf("expected a, got %q", got)
+	}
+	fc.move(redis.HashSlot("foo"), nodes[1])
+	if got := whoami(t, c, "GET", "foo"); got != "b" {
+		t.Fatalf("after MOVED: expected b, got %q", got)
+	}
+
+	// One before/after pair per command, whatever the redirections, and
+	// dials to both nodes.
+	var process, dials int
+	for _, line := range trace() {
+		switch line {
+		case "h:before GET", "h:after a", "h:after b":
+			process++
+		case "h:dial tcp":
+			dials++
+		default:
+			t.Fatalf("unexpected trace line %q", line)
+		}
+	}
+	if process != 4 || dials < 2 {
+		t.Fatalf("expected 4 process and at least 2 dial events, got %d and %d", process, dials)
+	}
+}
@@ -0,0 +1,378 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cmd is a command queued in a Pipeline. Its reply is available after Exec.
+type Cmd struct {
+	args []any
+	val  any
+	err  error
+}
+
+// Args returns the command arguments, including the command name.
+func (cmd *Cmd) Args() []any { return cmd.args }
+
+// Name returns the upper-cased command name.
+func (cmd *Cmd) Name() string {
+	if len(cmd.args) == 0 {
+		return ""
+	}
+	s, _ := cmd.args[0].(string)
+	return strings.ToUpper(s)
+}
+
+// Val returns the raw reply.
+func (cmd *Cmd) Val() any { return cmd.val }
+
+// Err returns the command error, either a RedisError reply or an I/O error.
+func (cmd *Cmd) Err() error { return cmd.err }
+
+// Result returns the raw reply and error.
+func (cmd *Cmd) Result() (any, error) { return cmd.val, cmd.err }
+
+// Text returns the reply as a string. A nil reply yields an empty string.
+func (cmd *Cmd) Text() (string, error) {
+	if cmd.err != nil {
+		return "", cmd.err
+	}
+	if cmd.val == nil {
+		return "", nil
+	}
+	s, ok := cmd.val.(string)
+	if !ok {
+		return "", fmt.Errorf("redis: unexpected type %T from %s", cmd.val, cmd.Name())
+	}
+	return s, nil
+}
+
+// Int64 returns the reply as an integer.
+func (cmd *Cmd) Int64() (int64, error) {
+	if cmd.err != nil {
+		return 0, cmd.err
+	}
+	n, ok := cmd.val.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected type %T from %s", cmd.val, cmd.Name())
+	}
+	return n, nil
+}
+
+// Bool returns true when the reply is the integer 1.
+func (cmd *Cmd) Bool() (bool, error) {
+	n, err := cmd.Int64()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Strings returns an array or set reply as a string slice.
+func (cmd *Cmd) Strings() ([]string, error) {
+	if cmd.err != nil {
+		return nil, cmd.err
+	}
+	return stringSlice(cmd.val, cmd.Name())
+}
+
+// StringMap returns a flat field/value array or map reply as a map.
+func (cmd *Cmd) StringMap() (map[string]string, error) {
+	if cmd.err != nil {
+		return nil, cmd.err
+	}
+	return stringMap(cmd.val, cmd.Name())
+}
+
+// Pipeline queues commands and sends them to Redis in a single round trip
+// on one pooled connection. A Pipeline is not safe for concurrent use.
+type Pipeline struct {
+	c     *Client
+	tx    *Tx  // non-nil when bound to the pinned connection of a Watch
+	multi bool // wrap the queued commands in MULTI/EXEC
+	cmds  []*Cmd
+}
+
+// Pipeline returns a new, empty pipeline bound to c.
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{c: c}
+}
+
+// Pipelined queues the commands issued by fn and executes them.
+func (c *Client) Pipelined(ctx context.Context, fn func(p *Pipeline) error) ([]*Cmd, error) {
+	p := c.Pipeline()
+	if err := fn(p); err != nil {
+		return nil, err
+	}
+	return p.Exec(ctx)
+}
+
+// Do queues a raw command.
+func (p *Pipeline) Do(args ...any) *Cmd {
+	cmd := &Cmd{args: args}
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// Len returns the number of queued commands.
+func (p *Pipeline) Len() int { return len(p.cmds) }
+
+// Discard drops all queued commands.
+func (p *Pipeline) Discard() { p.cmds = nil }
+
+// Exec sends all queued commands and reads their replies. The pipeline is
+// reset afterwards. The returned error is the first command error, if any;
+// per-command results are available on each Cmd.
+func (p *Pipeline) Exec(ctx context.Context) ([]*Cmd, error) {
+	cmds := p.cmds
+	p.cmds = nil
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+	start := time.Now()
+	err := p.c.processPipelineHook(p.exec)(ctx, cmds)
+	p.c.root().stats.observeCommand("PIPELINE", time.Since(start))
+	return cmds, err
+}
+
+// exec sends cmds without hooks and returns an I/O error or the first
+// command error.
+func (p *Pipeline) exec(ctx context.Context, cmds []*Cmd) error {
+	select {
+	case <-ctx.Done():
+		setCmdsErr(cmds, ctx.Err())
+		return ctx.Err()
+	default:
+	}
+
+	if p.tx != nil {
+		if err := p.tx.execPipeline(ctx, cmds); err != nil {
+			return err
+		}
+		return firstCmdErr(cmds)
+	}
+
+	c := p.c
+	if c.cluster != nil {
+		node, err := c.cluster.cmdsNode(ctx, cmds)
+		if err != nil {
+			setCmdsErr(cmds, err)
+			return err
+		}
+		c = node
+	}
+
+	cn, err := c.getConn(ctx)
+	if err != nil {
+		setCmdsErr(cmds, err)
+		if p.c.cluster != nil && needsReload(err) {
+			p.c.cluster.lazyReload()
+		}
+		return err
+	}
+	if p.multi {
+		err = c.txPipelineOn(ctx, cn, cmds)
+	} else {
+		err = c.pipelineOn(ctx, cn, cmds)
+	}
+	if err != nil {
+		c.removeConn(cn) // discard broken connection
+	} else {
+		c.putConn(cn)
+		err = firstCmdErr(cmds)
+	}
+	if err != nil && p.c.cluster != nil && needsReload(err) {
+		p.c.cluster.lazyReload()
+	}
+	return err
+}
+
+// pipelineOn writes all commands in one batch and reads one reply per command.
+// A non-nil return value is an I/O error that leaves cn unusable.
+func (c *Client) pipelineOn(ctx context.Context, cn *conn, cmds []*Cmd) error {
+	select {
+	case <-ctx.Done():
+		setCmdsErr(cmds, ctx.Err())
+		return ctx.Err()
+	default:
+	}
+	defer cn.watch(ctx)()
+
+	cn.setWriteDeadline(ctx, ioDeadline(ctx, c.opts.writeTimeout()))
+	for _, cmd := range cmds {
+		if err := WriteCommand(cn.wr, cmd.args...); err != nil {
+			err = ioErr(ctx, err)
+			setCmdsErr(cmds, err)
+			return err
+		}
+	}
+	if err := cn.wr.Flush(); err != nil {
+		err = ioErr(ctx, err)
+		setCmdsErr(cmds, err)
+		return err
+	}
+
+	rt := c.opts.readTimeout()
+	for i, cmd := range cmds {
+		cn.setReadDeadline(ctx, ioDeadline(ctx, rt))
+		reply, err := readReply(cn)
+		if err != nil {
+			err = ioErr(ctx, err)
+			setCmdsErr(cmds[i:], err)
+			return err
+		}
+		if e, ok := reply.(RedisError); ok {
+			cmd.err = e
+			continue
+		}
+		cmd.val = reply
+	}
+	return nil
+}
+
+func setCmdsErr(cmds []*Cmd, err error) {
+	for _, cmd := range cmds {
+		if cmd.err == nil {
+			cmd.err = err
+		}
+	}
+}
+
+func firstCmdErr(cmds []*Cmd) error {
+	for _, cmd := range cmds {
+		if cmd.err != nil {
+			return cmd.err
+		}
+	}
+	return nil
+}
+
+// --- Queued commands ---
+
+// Ping queues a PING command.
+func (p *Pipeline) Ping() *Cmd { return p.Do("PING") }
+
+// Get queues a GET command.
+func (p *Pipeline) Get(key string) *Cmd { return p.Do("GET", key) }
+
+// Set queues a SET command. If ttl > 0, sets an expiry.
+func (p *Pipeline) Set(key, value string, ttl time.Duration) *Cmd {
+	if ttl > 0 {
+		return p.Do("SET", key, value, "PX", ttl.Milliseconds())
+	}
+	return p.Do("SET", key, value)
+}
+
+// Del queues a DEL command.
+func (p *Pipeline) Del(keys ...string) *Cmd { return p.Do(keyArgs("DEL", keys)...) }
+
+// Exists queues an EXISTS command.
+func (p *Pipeline) Exists(keys ...string) *Cmd { return p.Do(keyArgs("EXISTS", keys)...) }
+
+// Incr queues an INCR command.
+func (p *Pipeline) Incr(key string) *Cmd { return p.Do("INCR", key) }
+
+// IncrBy queues an INCRBY command.
+func (p *Pipeline) IncrBy(key string, delta int64) *Cmd { return p.Do("INCRBY", key, delta) }
+
+// Expire queues an EXPIRE command.
+func (p *Pipeline) Expire(key string, ttl time.Duration) *Cmd {
+	return p.Do("EXPIRE", key, int64(ttl.Seconds()))
+}
+
+// TTL queues a TTL command.
+func (p *Pipeline) TTL(key string) *Cmd { return p.Do("TTL", key) }
+
+// HSet queues an HSET command.
+func (p *Pipeline) HSet(key, field, value string) *Cmd { return p.Do("HSET", key, field, value) }
+
+// HGet queues an HGET command.
+func (p *Pipeline) HGet(key, field string) *Cmd { return p.Do("HGET", key, field) }
+
+// HGetAll queues an HGETALL command.
+func (p *Pipeline) HGetAll(key string) *Cmd { return p.Do("HGETALL", key) }
+
+// HDel queues an HDEL command.
+func (p *Pipeline) HDel(key string, fields ...string) *Cmd {
+	return p.Do(keyArgs("HDEL", fields, key)...)
+}
+
+// HExists queues an HEXISTS command.
+func (p *Pipeline) HExists(key, field string) *Cmd { return p.Do("HEXISTS", key, field) }
+
+// LPush queues an LPUSH command.
+func (p *Pipeline) LPush(key string, values ...string) *Cmd {
+	return p.Do(keyArgs("LPUSH", values, key)...)
+}
+
+// RPush queues an RPUSH command.
+func (p *Pipeline) RPush(key string, values ...string) *Cmd {
+	return p.Do(keyArgs("RPUSH", values, key)...)
+}
+
+// LPop queues an LPOP command.
+func (p *Pipeline) LPop(key string) *Cmd { return p.Do("LPOP", key) }
+
+// RPop queues an RPOP command.
+func (p *Pipeline) RPop(key string) *Cmd { return p.Do("RPOP", key) }
+
+// LLen queues an LLEN command.
+func (p *Pipeline) LLen(key string) *Cmd { return p.Do("LLEN", key) }
+
+// LRange queues an LRANGE command.
+func (p *Pipeline) LRange(key string, start, stop int64) *Cmd {
+	return p.Do("LRANGE", key, start, stop)
+}
+
+// SAdd queues an SADD command.
+func (p *Pipeline) SAdd(key string, members ...string) *Cmd {
+	return p.Do(keyArgs("SADD", members, key)...)
+}
+
+// SMembers queues an SMEMBERS command.
+func (p *Pipeline) SMembers(key string) *Cmd { return p.Do("SMEMBERS", key) }
+
+// SRem queues an SREM command.
+func (p *Pipeline) SRem(key string, members ...string) *Cmd {
+	return p.Do(keyArgs("SREM", members, key)...)
+}
+
+// SIsMember queues an SISMEMBER command.
+func (p *Pipeline) SIsMember(key, member string) *Cmd { return p.Do("SISMEMBER", key, member) }
+
+// SCard queues an SCARD command.
+func (p *Pipeline) SCard(key string) *Cmd { return p.Do("SCARD", key) }
+
+// Eval queues an EVAL command.
+func (p *Pipeline) Eval(script string, keys []string, args ...any) *Cmd {
+	return p.Do(scriptArgs("EVAL", script, keys, args)...)
+}
+
+// EvalSha queues an EVALSHA command.
+func (p *Pipeline) EvalSha(sha string, keys []string, args ...any) *Cmd {
+	return p.Do(scriptArgs("EVALSHA", sha, keys, args)...)
+}
+
+// keyArgs builds "name [prefix...] items..." as a command argument slice.
+func keyArgs(name string, items []string, prefix ...any) []any {
+	args := make([]any, 0, 1+len(prefix)+len(items))
+	args = append(args, name)
+	args = append(args, prefix...)
+	for _, it := range items {
+		args = append(args, it)
+	}
+	return args
+}
+
+// scriptArgs builds "EVAL|EVALSHA script numkeys keys... args..." as a command argument slice.
+func scriptArgs(name, script string, keys []string, args []any) []any {
+	cmd := make([]any, 0, 3+len(keys)+len(args))
+	cmd = append(cmd, name, script, len(keys))
+	for _, k := range keys {
+		cmd = append(cmd, k)
+	}
+	return append(cmd, args...)
+}
@@ -0,0 +1,208 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+func TestPipeline_Exec(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	p := c.Pipeline()
+	set := p.Set("pk", "v1", time.Minute)
+	get := p.Get("pk")
+	incr := p.Incr("pcounter")
+	hset := p.HSet("ph", "f", "v")
+	hall := p.HGetAll("ph")
+	rpush := p.RPush("pl", "a", "b")
+	lrange := p.LRange("pl", 0, -1)
+	missing := p.Get("pmissing")
+	exists := p.HExists("ph", "f")
+
+	if p.Len() != 9 {
+		t.Fatalf("expected 9 queued commands, got %d", p.Len())
+	}
+
+	cmds, err := p.Exec(ctx)
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if len(cmds) != 9 {
+		t.Fatalf("expected 9 results, got %d", len(cmds))
+	}
+	if p.Len() != 0 {
+		t.Fatal("pipeline should be reset after Exec")
+	}
+
+	if s, _ := set.Text(); s != "OK" {
+		t.Fatalf("SET: expected OK, got %q", s)
+	}
+	if s, _ := get.Text(); s != "v1" {
+		t.Fatalf("GET: expected v1, got %q", s)
+	}
+	if n, _ := incr.Int64(); n != 1 {
+		t.Fatalf("INCR: expected 1, got %d", n)
+	}
+	if err := hset.Err(); err != nil {
+		t.Fatalf("HSET: %v", err)
+	}
+	if m, _ := hall.StringMap(); m["f"] != "v" {
+		t.Fatalf("HGETALL: unexpected %v", m)
+	}
+	if n, _ := rpush.Int64(); n != 2 {
+		t.Fatalf("RPUSH: expected 2, got %d", n)
+	}
+	if items, _ := lrange.Strings(); len(items) != 2 || items[0] != "a" || items[1] != "b" {
+		t.Fatalf("LRANGE: unexpected %v", items)
+	}
+	if s, err := missing.Text(); err != nil || s != "" {
+		t.Fatalf("GET missing: expected empty, got %q, %v", s, err)
+	}
+	if ok, _ := exists.Bool(); !ok {
+		t.Fatal("HEXISTS: expected true")
+	}
+}
+
+func TestPipeline_CommandError(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	p := c.Pipeline()
+	p.Set("pstr", "v", 0)
+	bad := p.LPush("pstr", "x")
+	after := p.Get("pstr")
+
+	_, err := p.Exec(ctx)
+	if !errors.Is(err, redis.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType from Exec, got %v", err)
+	}
+	if !errors.Is(bad.Err(), redis.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType on LPUSH, got %v", bad.Err())
+	}
+	if s, err := after.Text(); err != nil || s != "v" {
+		t.Fatalf("command after error: expected v, got %q, %v", s, err)
+	}
+
+	// The connection must still be usable after a command error.
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
+func TestPipeline_Bulk(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	cmds, err := c.Pipelined(ctx, func(p *redis.Pipeline) error {
+		for i := range 1000 {
+			p.HSet("bulk", strconv.Itoa(i), strconv.Itoa(i))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Pipelined: %v", err)
+	}
+	if len(cmds) != 1000 {
+		t.Fatalf("expected 1000 results, got %d", len(cmds))
+	}
+
+	all, err := c.HGetAll(ctx, "bulk")
+	if err != nil {
+		t.Fatalf("HGetAll: %v", err)
+	}
+	if len(all) != 1000 {
+		t.Fatalf("expected 1000 fields, got %d", len(all))
+	}
+}
+
+func TestPipeline_Empty(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+
+	cmds, err := c.Pipeline().Exec(context.Background())
+	if err != nil || cmds != nil {
+		t.Fatalf("expected nil, nil for empty pipeline, got %v, %v", cmds, err)
+	}
+}
+
+func TestPipeline_ContextCanceled(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := c.Pipeline()
+	ping := p.Ping()
+	_, err := p.Exec(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !errors.Is(ping.Err(), context.Canceled) {
+		t.Fatalf("expected queued command to carry context.Canceled, got %v", ping.Err())
+	}
+}
+
+func TestPipeline_ContextCancelInFlight(t *testing.T) {
+	s := slowServer(t)
+	c := redis.NewClient(&redis.Options{Addr: s.addr()})
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	p := c.Pipeline()
+	get := p.Get("k")
+	slow := p.Do("SLOW")
+	if _, err := p.Exec(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Canceled, got %v", err)
+	}
+	if v, err := get.Text(); err != nil || v != "a" {
+		t.Fatalf("expected the first reply to be kept, got %q, %v", v, err)
+	}
+	if !errors.Is(slow.Err(), context.Canceled) {
+		t.Fatalf("expected Canceled on the pending command, got %v", slow.Err())
+	}
+	if st := c.Stats(); st.Active != 0 {
+		t.Fatalf("expected the connection to be discarded, got %+v", st.PoolStats)
+	}
+}
+
+func TestPipeline_ContextCancelBeforeRead(t *testing.T) {
+	s := slowServer(t)
+	c := redis.NewClient(&redis.Options{Addr: s.addr(), ReadTimeout: -1, MaxRetries: -1})
+	defer c.Close()
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	// Deadlines: the write, then the read of each reply.
+	ctx := newCancelAtDeadline(3)
+	p := c.Pipeline()
+	get := p.Get("k")
+	p.Do("SLOW")
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Exec(ctx)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the read deadline overwrote the cancellation")
+	}
+	if v, err := get.Text(); err != nil || v != "a" {
+		t.Fatalf("expected the first reply to be kept, got %q, %v", v, err)
+	}
+}
@@ -0,0 +1,361 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+func TestClient_PoolTimeout(t *testing.T) {
+	s := nameServer(t, "a")
+	c := redis.NewClient(&redis.Options{Addr: s.addr(), PoolSize: 1, PoolTimeout: 30 * time.Millisecond})
+	defer c.Close()
+	ctx := context.Background()
+
+	// With the only connection pinned, the waiter gives up after
+	// PoolTimeout although its context has no deadline.
+	start := time.Now()
+	err := c.Watch(ctx, func(*redis.Tx) error {
+		return c.Ping(ctx)
+	})
+	if !errors.Is(err, redis.ErrPoolTimeout) {
+		t.Fatalf("expected ErrPoolTimeout, got %v", err)
+	}
+	if d := time.Since(start); d < 30*time.Millisecond || d > time.Second {
+		t.Fatalf("waited %v, expected about 30ms", d)
+	}
+	if st := c.Stats(); st.Timeouts != 1 || st.Waiters != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+
+	// An earlier context deadline still wins.
+	err = c.Watch(ctx, func(*redis.Tx) error {
+		wctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+		defer cancel()
+		return c.Ping(wctx)
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+}
+
+// taggedConn records the dial order of the last connection written to.
+type taggedConn struct {
+	net.Conn
+	id   int32
+	last *atomic.Int32
+}
+
+func (c *taggedConn) Write(b []byte) (int, error) {
+	c.last.Store(c.id)
+	return c.Conn.Write(b)
+}
+
+func TestClient_PoolFIFO(t *testing.T) {
+	s := nameServer(t, "a")
+	for _, tc := range []struct {
+		fifo bool
+		want int32
+	}{
+		{false, 1}, // the last connection returned
+		{true, 2},  // the first connection returned
+	} {
+		var dials, last atomic.Int32
+		c := redis.NewClient(&redis.Options{
+			Addr:     s.addr(),
+			PoolSize: 2,
+			PoolFIFO: tc.fifo,
+			Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var d net.Dialer
+				nc, err := d.DialContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				return &taggedConn{Conn: nc, id: dials.Add(1), last: &last}, nil
+			},
+		})
+
+		// Dial connections 1 and 2; 2 is returned to the pool first.
+		err := c.Watch(context.Background(), func(*redis.Tx) error {
+			return c.Watch(context.Background(), func(*redis.Tx) error { return nil })
+		})
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+		whoami(t, c, "WHO")
+		if got := last.Load(); got != tc.want {
+			t.Fatalf("PoolFIFO=%v: expected connection %d, got %d", tc.fifo, tc.want, got)
+		}
+		c.Close()
+	}
+}
+
+func TestClient_PoolWaiterRedial(t *testing.T) {
+	s := newFakeServer(t, func(args []string) string {
+		if args[0] == "HANG" {
+			return "" // never replies
+		}
+		return bulk("a")
+	})
+	c := redis.NewClient(&redis.Options{
+		Addr:        s.addr(),
+		PoolSize:    1,
+		PoolTimeout: -1,
+		ReadTimeout: 100 * time.Millisecond,
+	})
+	defer c.Close()
+	ctx := context.Background()
+
+	hung := make(chan error, 1)
+	go func() {
+		_, err := c.Do(ctx, "HANG")
+		hung <- err
+	}()
+	for st := c.PoolStats(); st.Active != 1 || st.Idle != 0; st = c.PoolStats() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The pinned connection is discarded on its read timeout; the waiter
+	// gets a fresh one instead of waiting for its context.
+	wctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if got, err := c.Do(wctx, "WHO"); err != nil || got != "a" {
+		t.Fatalf("expected a, got %v, %v", got, err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("waiter took %v", d)
+	}
+	if err := <-hung; err == nil {
+		t.Fatal("expected a read timeout")
+	}
+	if st := c.Stats(); st.Dials != 2 || st.ErrorConns != 1 || st.Active != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestClient_MinIdle(t *testing.T) {
+	s := nameServer(t, "a")
+	c := redis.NewClient(&redis.Options{Addr: s.addr(), PoolSize: 5, MinIdle: 3})
+	defer c.Close()
+
+	if err := c.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup: %v", err)
+	}
+	if st := c.Stats(); st.Idle != 3 || st.Active != 3 || st.Dials != 3 {
+		t.Fatalf("expected 3 idle connections, got %+v", st)
+	}
+	whoami(t, c, "WHO")
+	if st := c.Stats(); st.Hits != 1 || st.Misses != 0 {
+		t.Fatalf("expected the command to reuse a warm connection, got %+v", st)
+	}
+}
+
+func TestClient_MinIdlePrewarm(t *testing.T) {
+	s := nameServer(t, "a")
+	c := redis.NewClient(&redis.Options{Addr: s.addr(), MinIdle: 2})
+	defer c.Close()
+
+	// Dialed in the background without Warmup.
+	deadline := time.Now().Add(5 * time.Second)
+	for c.PoolStats().Idle != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("pool not warmed: %+v", c.PoolStats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestClient_WarmupError(t *testing.T) {
+	errDial := errors.New("refused")
+	var fail atomic.Bool
+	fail.Store(true)
+	reported := make(chan error, 10)
+	s := nameServer(t, "a")
+	c := redis.NewClient(&redis.Options{
+		Addr:    s.addr(),
+		MinIdle: 2,
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if fail.Load() {
+				return nil, errDial
+			}
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+		OnWarmupError: func(err error) { reported <- err },
+	})
+	defer c.Close()
+	ctx := context.Background()
+
+	// The background dial of NewClient reports its error.
+	select {
+	case err := <-reported:
+		if !errors.Is(err, errDial) {
+			t.Fatalf("expected %v, got %v", errDial, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnWarmupError was not called")
+	}
+
+	if err := c.Warmup(ctx); !errors.Is(err, errDial) {
+		t.Fatalf("expected %v, got %v", errDial, err)
+	}
+	if st := c.Stats(); st.Active != 0 || st.DialErrors == 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+
+	// The reaper replenishes the pool once dials succeed again.
+	fail.Store(false)
+	c.Reap()
+	if st := c.PoolStats(); st.Idle != 2 {
+		t.Fatalf("expected 2 idle connections after reaping, got %+v", st)
+	}
+	if err := c.Warmup(ctx); err != nil {
+		t.Fatalf("Warmup: %v", err)
+	}
+}
+
+func TestClient_WarmupWithoutMinIdle(t *testing.T) {
+	s := nameServer(t, "a")
+	c := redis.NewClient(&redis.Options{Addr: s.addr()})
+	defer c.Close()
+
+	if err := c.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup: %v", err)
+	}
+	if st := c.Stats(); st.Dials != 0 {
+		t.Fatalf("expected no dial without MinIdle, got %d", st.Dials)
+	}
+}
+
+func TestClient_WarmupClosed(t *testing.T) {
+	c := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	c.Close()
+	if err := c.Warmup(context.Background()); err == nil {
+		t.Fatal("expected an error on a closed client")
+	}
+}
+
+func TestClusterClient_Warmup(t *testing.T) {
+	_, nodes := newFakeCluster(t, "a", "b")
+	c := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs:   []string{nodes[0].addr()},
+		Options: redis.Options{MinIdle: 2},
+	})
+	defer c.Close()
+
+	if err := c.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup: %v", err)
+	}
+	masters := 0
+	err := c.ForEachMaster(context.Background(), func(_ context.Context, node *redis.Client) error {
+		masters++
+		if st := node.PoolStats(); st.Idle < 2 {
+			t.Errorf("expected 2 idle connections, got %+v", st)
+		}
+		return nil
+	})
+	if err != nil || masters != 2 {
+		t.Fatalf("ForEachMaster: %d masters, %v", masters, err)
+	}
+}
+
+func TestClient_HealthCheckProbe(t *testing.T) {
+	for _, hc := range []time.Duration{0, time.Millisecond} {
+		s := nameServer(t, "a")
+		c := redis.NewClient(&redis.Options{Addr: s.addr(), HealthCheckInterval: hc})
+		whoami(t, c, "INCR", "n")
+		s.dropConns()
+		time.Sleep(10 * time.Millisecond)
+
+		// INCR is not retried: without health checks it fails on the dead
+		// connection, with them the dead connection is never handed out.
+		_, err := c.Do(context.Background(), "INCR", "n")
+		if hc == 0 {
+			if err == nil {
+				t.Fatal("expected an error on the dropped connection")
+			}
+		} else {
+			if err != nil {
+				t.Fatalf("INCR: %v", err)
+			}
+			if st := c.Stats(); st.ErrorConns != 1 || st.Dials != 2 {
+				t.Fatalf("expected the dead connection to be replaced, got %+v", st)
+			}
+		}
+		c.Close()
+	}
+}
+
+func TestClient_HealthCheckPing(t *testing.T) {
+	s := nameServer(t, "a")
+	c := redis.NewClient(&redis.Options{Addr: s.addr(), HealthCheckInterval: 5 * time.Millisecond})
+	defer c.Close()
+
+	// The reaper pings the idle connection every 5ms and keeps it.
+	whoami(t, c, "WHO")
+	time.Sleep(30 * time.Millisecond)
+	if st := c.Stats(); st.Active != 1 || st.ErrorConns != 0 {
+		t.Fatalf("expected the live connection to be kept, got %+v", st)
+	}
+
+	s.dropConns()
+	deadline := time.Now().Add(5 * time.Second)
+	for st := c.Stats(); st.Active != 0 || st.ErrorConns != 1; st = c.Stats() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the dropped connection to be closed, got %+v", st)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// unsentConn fails the next write before sending anything.
+type unsentConn struct {
+	net.Conn
+	fail *atomic.Bool
+}
+
+func (c unsentConn) Write(b []byte) (int, error) {
+	if c.fail.CompareAndSwap(true, false) {
+		return 0, errors.New("broken pipe")
+	}
+	return c.Conn.Write(b)
+}
+
+func TestClient_RetryUnsent(t *testing.T) {
+	var fail atomic.Bool
+	s := nameServer(t, "a")
+	c := redis.NewClient(&redis.Options{
+		Addr: s.addr(),
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var d net.Dialer
+			nc, err := d.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return unsentConn{Conn: nc, fail: &fail}, nil
+		},
+	})
+	defer c.Close()
+	ctx := context.Background()
+
+	// GET is idempotent: retried once on a new connection.
+	fail.Store(true)
+	if got, err := c.Get(ctx, "k"); err != nil || got != "a" {
+		t.Fatalf("Get: %q, %v", got, err)
+	}
+	if st := c.Stats(); st.Dials != 2 || st.ErrorConns != 1 {
+		t.Fatalf("expected one redial, got %+v", st)
+	}
+
+	// INCR is not.
+	fail.Store(true)
+	if _, err := c.Do(ctx, "INCR", "n"); err == nil {
+		t.Fatal("expected the write error")
+	}
+}
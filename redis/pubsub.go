@@ -0,0 +1,368 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Pub/Sub settings.
+const (
+	pubsubPingInterval = 30 * time.Second
+	pubsubChannelSize  = 100
+	pubsubMinBackoff   = 100 * time.Millisecond
+	pubsubMaxBackoff   = 5 * time.Second
+)
+
+// Message is a message received on a subscribed channel.
+type Message struct {
+	Channel string
+	Pattern string // set for messages matched by PSubscribe
+	Payload string
+}
+
+// PubSub is a subscriber holding a dedicated connection outside the pool.
+// Messages are delivered on Channel(). The connection is pinged periodically;
+// when it breaks, PubSub reconnects and restores all subscriptions.
+type PubSub struct {
+	c            *Client
+	pingInterval time.Duration
+
+	mu       sync.Mutex // guards cn, channels, patterns and writes to cn
+	cn       *conn
+	channels map[string]struct{}
+	patterns map[string]struct{}
+
+	msgCh     chan *Message
+	exit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Subscribe subscribes to channels on a new dedicated connection.
+func (c *Client) Subscribe(ctx context.Context, channels ...string) (*PubSub, error) {
+	return c.newPubSub(ctx, channels, nil)
+}
+
+// PSubscribe subscribes to channel patterns on a new dedicated connection.
+func (c *Client) PSubscribe(ctx context.Context, patterns ...string) (*PubSub, error) {
+	return c.newPubSub(ctx, nil, patterns)
+}
+
+func (c *Client) newPubSub(ctx context.Context, channels, patterns []string) (*PubSub, error) {
+	if c.closed.Load() {
+		return nil, fmt.Errorf("redis: client is closed")
+	}
+	ps := &PubSub{
+		c:            c,
+		pingInterval: pubsubPingInterval,
+		channels:     make(map[string]struct{}),
+		patterns:     make(map[string]struct{}),
+		msgCh:        make(chan *Message, pubsubChannelSize),
+		exit:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	for _, ch := range channels {
+		ps.channels[ch] = struct{}{}
+	}
+	for _, p := range patterns {
+		ps.patterns[p] = struct{}{}
+	}
+
+	cn, err := c.dialConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := ps.resubscribe(cn); err != nil {
+		cn.nc.Close()
+		return nil, err
+	}
+	ps.cn = cn
+
+	go ps.run()
+	go ps.pinger()
+	return ps, nil
+}
+
+// Channel returns the channel on which messages are delivered.
+// It is closed when the PubSub is closed.
+func (ps *PubSub) Channel() <-chan *Message {
+	return ps.msgCh
+}
+
+// Subscribe adds channels to the subscription.
+func (ps *PubSub) Subscribe(ctx context.Context, channels ...string) error {
+	return ps.update(ctx, "SUBSCRIBE", ps.channels, channels, true)
+}
+
+// Unsubscribe removes channels from the subscription, or all channels if none are given.
+func (ps *PubSub) Unsubscribe(ctx context.Context, channels ...string) error {
+	return ps.update(ctx, "UNSUBSCRIBE", ps.channels, channels, false)
+}
+
+// PSubscribe adds channel patterns to the subscription.
+func (ps *PubSub) PSubscribe(ctx context.Context, patterns ...string) error {
+	return ps.update(ctx, "PSUBSCRIBE", ps.patterns, patterns, true)
+}
+
+// PUnsubscribe removes channel patterns from the subscription, or all patterns if none are given.
+func (ps *PubSub) PUnsubscribe(ctx context.Context, patterns ...string) error {
+	return ps.update(ctx, "PUNSUBSCRIBE", ps.patterns, patterns, false)
+}
+
+// update records a subscription change and sends it on the current connection.
+// If the connection is down, the change is applied on reconnect.
+func (ps *PubSub) update(ctx context.Context, name string, set map[string]struct{}, items []string, add bool) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	select {
+	case <-ps.exit:
+		return fmt.Errorf("redis: pubsub is closed")
+	default:
+	}
+
+	if add && len(items) == 0 {
+		return nil
+	}
+	if add {
+		for _, it := range items {
+			set[it] = struct{}{}
+		}
+	} else if len(items) == 0 {
+		clear(set)
+	} else {
+		for _, it := range items {
+			delete(set, it)
+		}
+	}
+
+	if ps.cn == nil {
+		return nil
+	}
+	if err := ps.writeLocked(keyArgs(name, items)...); err != nil {
+		ps.cn.nc.Close() // the reader reconnects and resubscribes
+		return err
+	}
+	return nil
+}
+
+// writeLocked writes a command to the current connection. ps.mu must be held.
+func (ps *PubSub) writeLocked(args ...any) error {
+	wt := ps.c.opts.writeTimeout()
+	if wt > 0 {
+		ps.cn.nc.SetWriteDeadline(time.Now().Add(wt))
+	}
+	return WriteCommand(ps.cn.nc, args...)
+}
+
+// resubscribe sends the current subscriptions on cn.
+func (ps *PubSub) resubscribe(cn *conn) error {
+	wt := ps.c.opts.writeTimeout()
+	if wt > 0 {
+		cn.nc.SetWriteDeadline(time.Now().Add(wt))
+	}
+	if len(ps.channels) > 0 {
+		if err := WriteCommand(cn.wr, setArgs("SUBSCRIBE", ps.channels)...); err != nil {
+			return err
+		}
+	}
+	if len(ps.patterns) > 0 {
+		if err := WriteCommand(cn.wr, setArgs("PSUBSCRIBE", ps.patterns)...); err != nil {
+			return err
+		}
+	}
+	return cn.wr.Flush()
+}
+
+func setArgs(name string, set map[string]struct{}) []any {
+	args := make([]any, 0, 1+len(set))
+	args = append(args, name)
+	for it := range set {
+		args = append(args, it)
+	}
+	return args
+}
+
+// pinger sends PING periodically so that run detects dead connections.
+func (ps *PubSub) pinger() {
+	ticker := time.NewTicker(ps.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ps.mu.Lock()
+			if ps.cn != nil {
+				if err := ps.writeLocked("PING"); err != nil {
+					ps.cn.nc.Close()
+				}
+			}
+			ps.mu.Unlock()
+		case <-ps.exit:
+			return
+		}
+	}
+}
+
+// run reads replies, delivers messages and reconnects on failure.
+func (ps *PubSub) run() {
+	defer close(ps.done)
+	defer close(ps.msgCh)
+
+	for {
+		ps.mu.Lock()
+		cn := ps.cn
+		ps.mu.Unlock()
+
+		ps.receive(cn) // returns once the connection fails or the PubSub is closed
+		select {
+		case <-ps.exit:
+			return
+		default:
+		}
+
+		ps.mu.Lock()
+		ps.cn = nil
+		ps.mu.Unlock()
+		cn.nc.Close()
+
+		if !ps.reconnect() {
+			return
+		}
+	}
+}
+
+// receive reads from cn until an error occurs. No data within two ping
+// intervals means the connection is dead.
+func (ps *PubSub) receive(cn *conn) {
+	for {
+		cn.nc.SetReadDeadline(time.Now().Add(2 * ps.pingInterval))
+		reply, err := ReadReply(cn.rd)
+		if err != nil {
+			return
+		}
+		msg := parseMessage(reply)
+		if msg == nil {
+			continue // subscription confirmation or PONG
+		}
+		select {
+		case ps.msgCh <- msg:
+		case <-ps.exit:
+			return
+		}
+	}
+}
+
+// reconnect dials until a new connection is subscribed. It returns false
+// when the PubSub or the client is closed.
+func (ps *PubSub) reconnect() bool {
+	backoff := pubsubMinBackoff
+	for {
+		select {
+		case <-ps.exit:
+			return false
+		case <-time.After(backoff):
+		}
+		if ps.c.closed.Load() {
+			return false
+		}
+
+		cn, err := ps.c.dialConn(context.Background())
+		if err == nil {
+			ps.mu.Lock()
+			select {
+			case <-ps.exit:
+				ps.mu.Unlock()
+				cn.nc.Close()
+				return false
+			default:
+			}
+			err = ps.resubscribe(cn)
+			if err == nil {
+				ps.cn = cn
+			}
+			ps.mu.Unlock()
+			if err == nil {
+				return true
+			}
+			cn.nc.Close()
+		}
+
+		backoff *= 2
+		if backoff > pubsubMaxBackoff {
+			backoff = pubsubMaxBackoff
+		}
+	}
+}
+
+// parseMessage converts a message or pmessage frame to a Message. Other
+// frames (subscribe confirmations, PONG) yield nil.
+func parseMessage(reply any) *Message {
+	var arr []any
+	switch v := reply.(type) {
+	case []any:
+		arr = v
+	case Push:
+		arr = v
+	default:
+		return nil
+	}
+	if len(arr) == 0 {
+		return nil
+	}
+	kind, _ := arr[0].(string)
+	switch kind {
+	case "message":
+		if len(arr) != 3 {
+			return nil
+		}
+		ch, _ := arr[1].(string)
+		payload, _ := arr[2].(string)
+		return &Message{Channel: ch, Payload: payload}
+	case "pmessage":
+		if len(arr) != 4 {
+			return nil
+		}
+		pattern, _ := arr[1].(string)
+		ch, _ := arr[2].(string)
+		payload, _ := arr[3].(string)
+		return &Message{Channel: ch, Pattern: pattern, Payload: payload}
+	}
+	return nil
+}
+
+// Close closes the dedicated connection, ending all subscriptions, and then
+// closes the message channel.
+func (ps *PubSub) Close() error {
+	var err error
+	ps.closeOnce.Do(func() {
+		ps.mu.Lock()
+		close(ps.exit)
+		if ps.cn != nil {
+			err = ps.cn.nc.Close()
+		}
+		ps.mu.Unlock()
+		<-ps.done
+	})
+	return err
+}
+
+// Publish posts a message to a channel and returns the number of receivers.
+func (c *Client) Publish(ctx context.Context, channel, message string) (int64, error) {
+	reply, err := c.Do(ctx, "PUBLISH", channel, message)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected type %T from PUBLISH", reply)
+	}
+	return n, nil
+}
@@ -0,0 +1,203 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+// waitNumSub waits until channel has n subscribers, as reported by PUBSUB NUMSUB.
+func waitNumSub(t *testing.T, c *redis.Client, channel string, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		reply, err := c.Do(context.Background(), "PUBSUB", "NUMSUB", channel)
+		if err != nil {
+			t.Fatalf("PUBSUB NUMSUB: %v", err)
+		}
+		if arr, ok := reply.([]any); ok && len(arr) == 2 && arr[1] == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d subscribers on %q", n, channel)
+}
+
+func receive(t *testing.T, ps *redis.PubSub) *redis.Message {
+	t.Helper()
+	select {
+	case msg, ok := <-ps.Channel():
+		if !ok {
+			t.Fatal("message channel closed")
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+	return nil
+}
+
+func TestPubSub_SubscribePublish(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	ps, err := c.Subscribe(ctx, "news")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer ps.Close()
+	waitNumSub(t, c, "news", 1)
+
+	n, err := c.Publish(ctx, "news", "hello")
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 receiver, got %d", n)
+	}
+
+	msg := receive(t, ps)
+	if msg.Channel != "news" || msg.Payload != "hello" || msg.Pattern != "" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	// The subscriber connection lives outside the pool.
+	if stats := c.PoolStats(); stats.Active != 1 {
+		t.Fatalf("expected 1 pooled connection, got %+v", stats)
+	}
+}
+
+func TestPubSub_PSubscribe(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	ps, err := c.PSubscribe(ctx, "events.*")
+	if err != nil {
+		t.Fatalf("PSubscribe: %v", err)
+	}
+	defer ps.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		n, _ := c.Publish(ctx, "events.login", "alice")
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for pattern subscription")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	msg := receive(t, ps)
+	if msg.Channel != "events.login" || msg.Pattern != "events.*" || msg.Payload != "alice" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+func TestPubSub_RuntimeSubscribe(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	ps, err := c.Subscribe(ctx, "a")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer ps.Close()
+
+	if err := ps.Subscribe(ctx, "b"); err != nil {
+		t.Fatalf("PubSub.Subscribe: %v", err)
+	}
+	waitNumSub(t, c, "b", 1)
+
+	if err := ps.Unsubscribe(ctx, "a"); err != nil {
+		t.Fatalf("PubSub.Unsubscribe: %v", err)
+	}
+	waitNumSub(t, c, "a", 0)
+
+	c.Publish(ctx, "a", "ignored")
+	c.Publish(ctx, "b", "kept")
+
+	msg := receive(t, ps)
+	if msg.Channel != "b" || msg.Payload != "kept" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+func TestPubSub_Reconnect(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	ps, err := c.Subscribe(ctx, "rc")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer ps.Close()
+	waitNumSub(t, c, "rc", 1)
+
+	ps.CloseConn()
+	waitNumSub(t, c, "rc", 0)
+	waitNumSub(t, c, "rc", 1)
+
+	c.Publish(ctx, "rc", "after")
+	msg := receive(t, ps)
+	if msg.Payload != "after" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+func TestPubSub_Close(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	ps, err := c.Subscribe(ctx, "closing")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := ps.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := ps.Close(); err != nil {
+		t.Fatalf("second Close should be nil: %v", err)
+	}
+
+	select {
+	case _, ok := <-ps.Channel():
+		if ok {
+			t.Fatal("expected closed channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed")
+	}
+
+	if err := ps.Subscribe(ctx, "more"); err == nil {
+		t.Fatal("expected error subscribing on closed PubSub")
+	}
+}
+
+func TestPubSub_RESP3(t *testing.T) {
+	addr := redisAddr(t)
+	c := redis.NewClient(&redis.Options{Addr: addr, PoolSize: 1, Protocol: 3})
+	defer c.Close()
+	ctx := context.Background()
+
+	ps, err := c.Subscribe(ctx, "r3")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer ps.Close()
+	waitNumSub(t, c, "r3", 1)
+
+	c.Publish(ctx, "r3", "push")
+	msg := receive(t, ps)
+	if msg.Channel != "r3" || msg.Payload != "push" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
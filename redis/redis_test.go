@@ -4,7 +4,14 @@ import (
 	"bytes"
 	"bufio"
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -82,6 +89,66 @@ func TestReadReply_Error(t *testing.T) {
 	}
 }
 
+func TestReadReply_ErrorCode(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader([]byte("-NOSCRIPT No matching script. Please use EVAL.\r\n")))
+	reply, err := redis.ReadReply(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := reply.(redis.RedisError)
+	if !ok {
+		t.Fatalf("expected RedisError, got %T", reply)
+	}
+	if e.Code() != "NOSCRIPT" {
+		t.Fatalf("expected code NOSCRIPT, got %q", e.Code())
+	}
+	if e.Message() != "No matching script. Please use EVAL." {
+		t.Fatalf("unexpected message %q", e.Message())
+	}
+	if !errors.Is(e, redis.ErrNoScript) {
+		t.Fatal("expected errors.Is(err, ErrNoScript)")
+	}
+	if errors.Is(e, redis.ErrWrongType) {
+		t.Fatal("NOSCRIPT must not match ErrWrongType")
+	}
+}
+
+func TestRedisError_Is(t *testing.T) {
+	tests := []struct {
+		reply  string
+		target error
+	}{
+		{"WRONGTYPE Operation against a key holding the wrong kind of value", redis.ErrWrongType},
+		{"BUSY Redis is busy running a script.", redis.ErrBusy},
+		{"MOVED 3999 127.0.0.1:6381", redis.ErrMoved},
+		{"ASK 3999 127.0.0.1:6381", redis.ErrAsk},
+		{"LOADING Redis is loading the dataset in memory", redis.ErrLoading},
+		{"READONLY You can't write against a read only replica.", redis.ErrReadOnly},
+		{"TRYAGAIN Multiple keys request during rehashing of slot", redis.ErrTryAgain},
+		{"NOAUTH Authentication required.", redis.ErrNoAuth},
+		{"EXECABORT Transaction discarded because of previous errors.", redis.ErrExecAbort},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", redis.RedisError(tt.reply))
+		if !errors.Is(err, tt.target) {
+			t.Errorf("%q: expected match with %v", tt.reply, tt.target)
+		}
+	}
+
+	generic := redis.RedisError("ERR unknown command")
+	if generic.Code() != "ERR" || generic.Message() != "unknown command" {
+		t.Fatalf("unexpected code/message %q / %q", generic.Code(), generic.Message())
+	}
+	if errors.Is(generic, redis.ErrNoScript) {
+		t.Fatal("ERR must not match any sentinel")
+	}
+
+	bare := redis.RedisError("KEY_NOT_FOUND")
+	if bare.Code() != "KEY_NOT_FOUND" || bare.Message() != "" {
+		t.Fatalf("unexpected code/message %q / %q", bare.Code(), bare.Message())
+	}
+}
+
 func TestReadReply_Integer(t *testing.T) {
 	r := bufio.NewReader(bytes.NewReader([]byte(":42\r\n")))
 	reply, err := redis.ReadReply(r)
@@ -159,6 +226,132 @@ func TestReadReply_InvalidType(t *testing.T) {
 	}
 }
 
+func readString(t *testing.T, data string) any {
+	t.Helper()
+	reply, err := redis.ReadReply(bufio.NewReader(bytes.NewReader([]byte(data))))
+	if err != nil {
+		t.Fatalf("ReadReply(%q): %v", data, err)
+	}
+	return reply
+}
+
+func TestReadReply_RESP3Null(t *testing.T) {
+	if reply := readString(t, "_\r\n"); reply != nil {
+		t.Fatalf("expected nil, got %v", reply)
+	}
+}
+
+func TestReadReply_RESP3Boolean(t *testing.T) {
+	if reply := readString(t, "#t\r\n"); reply != true {
+		t.Fatalf("expected true, got %v", reply)
+	}
+	if reply := readString(t, "#f\r\n"); reply != false {
+		t.Fatalf("expected false, got %v", reply)
+	}
+}
+
+func TestReadReply_RESP3Double(t *testing.T) {
+	if reply := readString(t, ",3.14\r\n"); reply != 3.14 {
+		t.Fatalf("expected 3.14, got %v", reply)
+	}
+	if reply := readString(t, ",inf\r\n"); !math.IsInf(reply.(float64), 1) {
+		t.Fatalf("expected +Inf, got %v", reply)
+	}
+	if reply := readString(t, ",-inf\r\n"); !math.IsInf(reply.(float64), -1) {
+		t.Fatalf("expected -Inf, got %v", reply)
+	}
+}
+
+func TestReadReply_RESP3BigNumber(t *testing.T) {
+	reply := readString(t, "(3492890328409238509324850943850943825024385\r\n")
+	n, ok := reply.(*big.Int)
+	if !ok {
+		t.Fatalf("expected *big.Int, got %T", reply)
+	}
+	if n.String() != "3492890328409238509324850943850943825024385" {
+		t.Fatalf("unexpected big number %s", n)
+	}
+}
+
+func TestReadReply_RESP3Verbatim(t *testing.T) {
+	reply := readString(t, "=15\r\ntxt:Some string\r\n")
+	v, ok := reply.(redis.Verbatim)
+	if !ok {
+		t.Fatalf("expected Verbatim, got %T", reply)
+	}
+	if v.Format != "txt" || v.Text != "Some string" {
+		t.Fatalf("unexpected verbatim %+v", v)
+	}
+}
+
+func TestReadReply_RESP3BlobError(t *testing.T) {
+	reply := readString(t, "!21\r\nSYNTAX invalid syntax\r\n")
+	e, ok := reply.(redis.RedisError)
+	if !ok {
+		t.Fatalf("expected RedisError, got %T", reply)
+	}
+	if e.Code() != "SYNTAX" || e.Message() != "invalid syntax" {
+		t.Fatalf("unexpected error %q", e)
+	}
+}
+
+func TestReadReply_RESP3Map(t *testing.T) {
+	reply := readString(t, "%2\r\n+first\r\n:1\r\n$6\r\nsecond\r\n*1\r\n:2\r\n")
+	m, ok := reply.(map[any]any)
+	if !ok {
+		t.Fatalf("expected map[any]any, got %T", reply)
+	}
+	if m["first"] != int64(1) {
+		t.Fatalf("unexpected first %v", m["first"])
+	}
+	if arr, ok := m["second"].([]any); !ok || len(arr) != 1 || arr[0] != int64(2) {
+		t.Fatalf("unexpected second %v", m["second"])
+	}
+}
+
+func TestReadReply_RESP3Set(t *testing.T) {
+	reply := readString(t, "~2\r\n+a\r\n+b\r\n")
+	set, ok := reply.(redis.Set)
+	if !ok {
+		t.Fatalf("expected Set, got %T", reply)
+	}
+	if len(set) != 2 || set[0] != "a" || set[1] != "b" {
+		t.Fatalf("unexpected set %v", set)
+	}
+}
+
+func TestReadReply_RESP3Push(t *testing.T) {
+	reply := readString(t, ">3\r\n+message\r\n+ch\r\n+hi\r\n")
+	push, ok := reply.(redis.Push)
+	if !ok {
+		t.Fatalf("expected Push, got %T", reply)
+	}
+	if len(push) != 3 || push[0] != "message" || push[2] != "hi" {
+		t.Fatalf("unexpected push %v", push)
+	}
+}
+
+func TestReadReply_RESP3Attribute(t *testing.T) {
+	reply := readString(t, "|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.19\r\n*1\r\n:2039123\r\n")
+	a, ok := reply.(redis.Attribute)
+	if !ok {
+		t.Fatalf("expected Attribute, got %T", reply)
+	}
+	if _, ok := a.Attrs["key-popularity"].(map[any]any); !ok {
+		t.Fatalf("unexpected attributes %v", a.Attrs)
+	}
+	if arr, ok := a.Reply.([]any); !ok || len(arr) != 1 || arr[0] != int64(2039123) {
+		t.Fatalf("unexpected reply %v", a.Reply)
+	}
+}
+
+func TestReadReply_RESP3UnhashableMapKey(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader([]byte("%1\r\n*1\r\n:1\r\n:2\r\n")))
+	if _, err := redis.ReadReply(r); err == nil {
+		t.Fatal("expected error for array map key")
+	}
+}
+
 // --- Integration tests (need Redis) ---
 
 func TestClient_PingClose(t *testing.T) {
@@ -218,6 +411,48 @@ func TestClient_PoolStats(t *testing.T) {
 	}
 }
 
+func TestClient_RESP3(t *testing.T) {
+	addr := redisAddr(t)
+	c := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		PoolSize: 1,
+		Protocol: 3,
+	})
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	c.HSet(ctx, "r3h", "f1", "v1")
+	c.HSet(ctx, "r3h", "f2", "v2")
+	all, err := c.HGetAll(ctx, "r3h")
+	if err != nil {
+		t.Fatalf("HGetAll: %v", err)
+	}
+	if len(all) != 2 || all["f1"] != "v1" || all["f2"] != "v2" {
+		t.Fatalf("HGetAll: unexpected %v", all)
+	}
+
+	c.SAdd(ctx, "r3s", "a", "b")
+	members, err := c.SMembers(ctx, "r3s")
+	if err != nil {
+		t.Fatalf("SMembers: %v", err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("SMembers: expected 2 members, got %v", members)
+	}
+
+	reply, err := c.Do(ctx, "HGETALL", "r3h")
+	if err != nil {
+		t.Fatalf("Do HGETALL: %v", err)
+	}
+	if _, ok := reply.(map[any]any); !ok {
+		t.Fatalf("expected RESP3 map reply, got %T", reply)
+	}
+}
+
 // --- String commands ---
 
 func TestClient_GetSet(t *testing.T) {
@@ -306,6 +541,18 @@ func TestClient_IncrIncrBy(t *testing.T) {
 	}
 }
 
+func TestClient_WrongTypeError(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	c.Set(ctx, "str", "v", 0)
+	_, err := c.LPush(ctx, "str", "x")
+	if !errors.Is(err, redis.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType, got %v", err)
+	}
+}
+
 // --- Key commands ---
 
 func TestClient_ExpireTTL(t *testing.T) {
@@ -453,6 +700,219 @@ func TestClient_Set_Commands(t *testing.T) {
 	}
 }
 
+// --- Sorted set commands ---
+
+func TestClient_SortedSet(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	n, err := c.ZAdd(ctx, "lb", redis.Z{Member: "alice", Score: 10}, redis.Z{Member: "bob", Score: 20}, redis.Z{Member: "carol", Score: 15.5})
+	if err != nil {
+		t.Fatalf("ZAdd: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("ZAdd: expected 3, got %d", n)
+	}
+
+	card, _ := c.ZCard(ctx, "lb")
+	if card != 3 {
+		t.Fatalf("ZCard: expected 3, got %d", card)
+	}
+
+	score, ok, err := c.ZScore(ctx, "lb", "carol")
+	if err != nil || !ok || score != 15.5 {
+		t.Fatalf("ZScore: expected 15.5, got %v %v %v", score, ok, err)
+	}
+	_, ok, err = c.ZScore(ctx, "lb", "nobody")
+	if err != nil || ok {
+		t.Fatalf("ZScore missing: expected !ok, got %v %v", ok, err)
+	}
+
+	scores, err := c.ZMScore(ctx, "lb", "alice", "nobody")
+	if err != nil {
+		t.Fatalf("ZMScore: %v", err)
+	}
+	if len(scores) != 2 || scores[0] == nil || *scores[0] != 10 || scores[1] != nil {
+		t.Fatalf("ZMScore: unexpected %v", scores)
+	}
+
+	newScore, err := c.ZIncrBy(ctx, "lb", 2.5, "alice")
+	if err != nil || newScore != 12.5 {
+		t.Fatalf("ZIncrBy: expected 12.5, got %v %v", newScore, err)
+	}
+
+	rank, ok, _ := c.ZRank(ctx, "lb", "alice")
+	if !ok || rank != 0 {
+		t.Fatalf("ZRank: expected 0, got %d %v", rank, ok)
+	}
+	rev, ok, _ := c.ZRevRank(ctx, "lb", "alice")
+	if !ok || rev != 2 {
+		t.Fatalf("ZRevRank: expected 2, got %d %v", rev, ok)
+	}
+	_, ok, _ = c.ZRank(ctx, "lb", "nobody")
+	if ok {
+		t.Fatal("ZRank missing: expected !ok")
+	}
+
+	members, _ := c.ZRange(ctx, "lb", 0, -1)
+	if len(members) != 3 || members[0] != "alice" || members[2] != "bob" {
+		t.Fatalf("ZRange: unexpected %v", members)
+	}
+
+	zs, err := c.ZRangeWithScores(ctx, "lb", 0, 0)
+	if err != nil || len(zs) != 1 || zs[0] != (redis.Z{Member: "alice", Score: 12.5}) {
+		t.Fatalf("ZRangeWithScores: unexpected %v %v", zs, err)
+	}
+
+	top, err := c.ZRangeArgsWithScores(ctx, "lb", redis.ZRangeArgs{Start: "+inf", Stop: "(12.5", ByScore: true, Rev: true, Count: 1})
+	if err != nil || len(top) != 1 || top[0].Member != "bob" {
+		t.Fatalf("ZRangeArgs BYSCORE REV LIMIT: unexpected %v %v", top, err)
+	}
+
+	count, _ := c.ZCount(ctx, "lb", "(12.5", "+inf")
+	if count != 2 {
+		t.Fatalf("ZCount: expected 2, got %d", count)
+	}
+
+	removed, _ := c.ZRem(ctx, "lb", "carol", "nobody")
+	if removed != 1 {
+		t.Fatalf("ZRem: expected 1, got %d", removed)
+	}
+}
+
+func TestClient_ZAddArgs(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	c.ZAdd(ctx, "za", redis.Z{Member: "m", Score: 5})
+
+	n, _ := c.ZAddArgs(ctx, "za", redis.ZAddArgs{NX: true}, redis.Z{Member: "m", Score: 1})
+	if n != 0 {
+		t.Fatalf("ZADD NX: expected 0, got %d", n)
+	}
+	n, _ = c.ZAddArgs(ctx, "za", redis.ZAddArgs{GT: true, CH: true}, redis.Z{Member: "m", Score: 7})
+	if n != 1 {
+		t.Fatalf("ZADD GT CH: expected 1 changed, got %d", n)
+	}
+	n, _ = c.ZAddArgs(ctx, "za", redis.ZAddArgs{XX: true}, redis.Z{Member: "new", Score: 1})
+	if n != 0 {
+		t.Fatalf("ZADD XX: expected 0, got %d", n)
+	}
+
+	score, ok, err := c.ZAddIncr(ctx, "za", redis.ZAddArgs{}, redis.Z{Member: "m", Score: 3})
+	if err != nil || !ok || score != 10 {
+		t.Fatalf("ZADD INCR: expected 10, got %v %v %v", score, ok, err)
+	}
+	_, ok, err = c.ZAddIncr(ctx, "za", redis.ZAddArgs{NX: true}, redis.Z{Member: "m", Score: 3})
+	if err != nil || ok {
+		t.Fatalf("ZADD NX INCR on existing member: expected !ok, got %v %v", ok, err)
+	}
+}
+
+func TestClient_ZPopAndRemRange(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	members := []redis.Z{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}, {"e", 5}}
+	c.ZAdd(ctx, "zp", members...)
+
+	low, err := c.ZPopMin(ctx, "zp", 2)
+	if err != nil || len(low) != 2 || low[0].Member != "a" || low[1].Score != 2 {
+		t.Fatalf("ZPopMin: unexpected %v %v", low, err)
+	}
+	high, err := c.ZPopMax(ctx, "zp", 1)
+	if err != nil || len(high) != 1 || high[0].Member != "e" {
+		t.Fatalf("ZPopMax: unexpected %v %v", high, err)
+	}
+
+	popped, err := c.BZPopMin(ctx, time.Second, "zp-empty", "zp")
+	if err != nil || popped == nil || popped.Key != "zp" || popped.Member != "c" || popped.Score != 3 {
+		t.Fatalf("BZPopMin: unexpected %+v %v", popped, err)
+	}
+
+	c.ZAdd(ctx, "zr", members...)
+	n, _ := c.ZRemRangeByRank(ctx, "zr", 0, 0)
+	if n != 1 {
+		t.Fatalf("ZRemRangeByRank: expected 1, got %d", n)
+	}
+	n, _ = c.ZRemRangeByScore(ctx, "zr", "4", "+inf")
+	if n != 2 {
+		t.Fatalf("ZRemRangeByScore: expected 2, got %d", n)
+	}
+
+	c.ZAdd(ctx, "zl", redis.Z{"apple", 0}, redis.Z{"banana", 0}, redis.Z{"cherry", 0})
+	lex, _ := c.ZRangeArgs(ctx, "zl", redis.ZRangeArgs{Start: "[b", Stop: "+", ByLex: true})
+	if len(lex) != 2 || lex[0] != "banana" {
+		t.Fatalf("ZRangeArgs BYLEX: unexpected %v", lex)
+	}
+	n, _ = c.ZRemRangeByLex(ctx, "zl", "-", "(banana")
+	if n != 1 {
+		t.Fatalf("ZRemRangeByLex: expected 1, got %d", n)
+	}
+}
+
+func TestClient_BZPopMinTimeout(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+
+	popped, err := c.BZPopMin(context.Background(), 100*time.Millisecond, "zempty")
+	if err != nil {
+		t.Fatalf("BZPopMin: %v", err)
+	}
+	if popped != nil {
+		t.Fatalf("expected nil on timeout, got %+v", popped)
+	}
+}
+
+func TestClient_ZSetOperations(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	c.ZAdd(ctx, "z1", redis.Z{"a", 1}, redis.Z{"b", 2})
+	c.ZAdd(ctx, "z2", redis.Z{"b", 3}, redis.Z{"c", 4})
+
+	union, err := c.ZUnionWithScores(ctx, redis.ZStore{Keys: []string{"z1", "z2"}})
+	if err != nil || len(union) != 3 {
+		t.Fatalf("ZUnionWithScores: unexpected %v %v", union, err)
+	}
+	for _, z := range union {
+		if z.Member == "b" && z.Score != 5 {
+			t.Fatalf("ZUnion: expected b=5, got %v", z.Score)
+		}
+	}
+
+	inter, err := c.ZInterWithScores(ctx, redis.ZStore{Keys: []string{"z1", "z2"}, Weights: []float64{2, 1}, Aggregate: "MAX"})
+	if err != nil || len(inter) != 1 || inter[0] != (redis.Z{Member: "b", Score: 4}) {
+		t.Fatalf("ZInterWithScores: unexpected %v %v", inter, err)
+	}
+	names, _ := c.ZInter(ctx, redis.ZStore{Keys: []string{"z1", "z2"}})
+	if len(names) != 1 || names[0] != "b" {
+		t.Fatalf("ZInter: unexpected %v", names)
+	}
+
+	diff, _ := c.ZDiff(ctx, "z1", "z2")
+	if len(diff) != 1 || diff[0] != "a" {
+		t.Fatalf("ZDiff: unexpected %v", diff)
+	}
+
+	n, err := c.ZUnionStore(ctx, "zu", redis.ZStore{Keys: []string{"z1", "z2"}})
+	if err != nil || n != 3 {
+		t.Fatalf("ZUnionStore: expected 3, got %d %v", n, err)
+	}
+	n, err = c.ZInterStore(ctx, "zi", redis.ZStore{Keys: []string{"z1", "z2"}})
+	if err != nil || n != 1 {
+		t.Fatalf("ZInterStore: expected 1, got %d %v", n, err)
+	}
+	n, err = c.ZDiffStore(ctx, "zd", "z1", "z2")
+	if err != nil || n != 1 {
+		t.Fatalf("ZDiffStore: expected 1, got %d %v", n, err)
+	}
+}
+
 // --- Script commands ---
 
 func TestClient_ScriptLoadExists(t *testing.T) {
@@ -627,3 +1087,144 @@ func TestClient_WrongPassword(t *testing.T) {
 	}
 }
 
+// slowServer replies "a" to every command, except SLOW, whose reply waits
+// for the end of the test.
+func slowServer(t *testing.T) *fakeServer {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	return newFakeServer(t, func(args []string) string {
+		if args[0] == "SLOW" {
+			<-release
+		}
+		return bulk("a")
+	})
+}
+
+func TestClient_ContextDeadline(t *testing.T) {
+	s := slowServer(t)
+	c := redis.NewClient(&redis.Options{Addr: s.addr()})
+	defer c.Close()
+
+	// The context deadline cuts the 3s ReadTimeout short.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.Do(ctx, "SLOW"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("returned after %v, expected about 50ms", d)
+	}
+	if st := c.Stats(); st.Active != 0 || st.ErrorConns != 1 {
+		t.Fatalf("expected the connection to be discarded, got %+v", st.PoolStats)
+	}
+
+	// ReadTimeout still applies when it comes first.
+	c2 := redis.NewClient(&redis.Options{Addr: s.addr(), ReadTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer c2.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, err := c2.Do(ctx, "SLOW")
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a read timeout, got %v", err)
+	}
+}
+
+// cancelAtDeadline is a context cancelled by its n-th Deadline call, as the
+// client computes the deadline of its n-th I/O operation. The call returns
+// once the cancellation had time to interrupt the connection, so the client
+// sets its deadline after that.
+type cancelAtDeadline struct {
+	context.Context
+	cancel context.CancelFunc
+	n      int32
+	calls  atomic.Int32
+}
+
+func newCancelAtDeadline(n int32) *cancelAtDeadline {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cancelAtDeadline{Context: ctx, cancel: cancel, n: n}
+}
+
+func (c *cancelAtDeadline) Deadline() (time.Time, bool) {
+	if c.calls.Add(1) == c.n {
+		c.cancel()
+		time.Sleep(20 * time.Millisecond)
+	}
+	return c.Context.Deadline()
+}
+
+func TestClient_ContextCancelBeforeWrite(t *testing.T) {
+	// The server never reads, so a large command blocks on write.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { nc.Close() })
+		}
+	}()
+	c := redis.NewClient(&redis.Options{
+		Addr:         ln.Addr().String(),
+		ReadTimeout:  -1,
+		WriteTimeout: -1,
+		MaxRetries:   -1,
+		MinIdle:      1,
+	})
+	defer c.Close()
+	if err := c.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Do(newCancelAtDeadline(1), "SET", "k", strings.Repeat("x", 64<<20))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the write deadline overwrote the cancellation")
+	}
+}
+
+func TestClient_ContextCancelInFlight(t *testing.T) {
+	s := slowServer(t)
+	c := redis.NewClient(&redis.Options{Addr: s.addr(), PoolSize: 1})
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := c.Do(ctx, "SLOW"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Canceled, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("returned after %v, expected about 50ms", d)
+	}
+	if st := c.Stats(); st.Active != 0 || st.ErrorConns != 1 {
+		t.Fatalf("expected the connection to be discarded, got %+v", st.PoolStats)
+	}
+
+	// Cancelling after the reply leaves the connection usable.
+	ctx, cancel = context.WithCancel(context.Background())
+	if got, err := c.Get(ctx, "k"); err != nil || got != "a" {
+		t.Fatalf("expected a, got %q, %v", got, err)
+	}
+	cancel()
+	if got, err := c.Get(context.Background(), "k"); err != nil || got != "a" {
+		t.Fatalf("expected a on the same connection, got %q, %v", got, err)
+	}
+	if st := c.Stats(); st.Dials != 2 || st.ErrorConns != 1 {
+		t.Fatalf("expected the connection to be reused, got %d dials", st.Dials)
+	}
+}
@@ -1,18 +1,39 @@
-// Package redis provides a minimal, zero-dependency Redis client using the RESP2 protocol.
+// Package redis provides a minimal, zero-dependency Redis client using the RESP2 protocol,
+// with opt-in RESP3 support.
 package redis
 
 import (
 	"bufio"
 	"fmt"
 	"io"
+	"math/big"
 	"strconv"
 	"sync"
 )
 
-// RedisError represents an error reply from Redis.
-type RedisError string
+// RESP3 reply types. Other replies map to Go types as follows:
+// simple and bulk strings to string, integers to int64, doubles to float64,
+// booleans to bool, big numbers to *big.Int, nulls to nil, arrays to []any
+// and maps to map[any]any.
+type (
+	// Set is a RESP3 set reply.
+	Set []any
 
-func (e RedisError) Error() string { return string(e) }
+	// Push is an out-of-band RESP3 push frame, e.g. a pub/sub message.
+	Push []any
+
+	// Verbatim is a RESP3 verbatim string with its three-letter format ("txt", "mkd").
+	Verbatim struct {
+		Format string
+		Text   string
+	}
+
+	// Attribute is a RESP3 reply preceded by an attribute map.
+	Attribute struct {
+		Attrs map[any]any
+		Reply any
+	}
+)
 
 var bufPool = sync.Pool{
 	New: func() any {
@@ -41,6 +62,8 @@ func WriteCommand(w io.Writer, args ...any) error {
 			s = strconv.Itoa(v)
 		case int64:
 			s = strconv.FormatInt(v, 10)
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
 		default:
 			s = fmt.Sprint(arg)
 		}
@@ -63,7 +86,7 @@ func WriteCommand(w io.Writer, args ...any) error {
 	return err
 }
 
-// ReadReply reads one RESP2 reply from r.
+// ReadReply reads one RESP2 or RESP3 reply from r.
 func ReadReply(r *bufio.Reader) (any, error) {
 	line, err := readLine(r)
 	if err != nil {
@@ -108,19 +131,113 @@ func ReadReply(r *bufio.Reader) (any, error) {
 		if n < 0 {
 			return nil, nil
 		}
-		arr := make([]any, n)
-		for i := range arr {
-			arr[i], err = ReadReply(r)
-			if err != nil {
-				return nil, err
-			}
+		return readArray(r, n)
+	case '_':
+		return nil, nil
+	case '#':
+		switch string(line[1:]) {
+		case "t":
+			return true, nil
+		case "f":
+			return false, nil
+		}
+		return nil, fmt.Errorf("redis: invalid boolean %q", line[1:])
+	case ',':
+		f, err := strconv.ParseFloat(string(line[1:]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid double %q", line[1:])
+		}
+		return f, nil
+	case '(':
+		n, ok := new(big.Int).SetString(string(line[1:]), 10)
+		if !ok {
+			return nil, fmt.Errorf("redis: invalid big number %q", line[1:])
+		}
+		return n, nil
+	case '!', '=':
+		n, err := parseAsciiInt(line[1:])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("redis: invalid blob length %q", line[1:])
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if line[0] == '!' {
+			return RedisError(buf[:n]), nil
+		}
+		if n < 4 || buf[3] != ':' {
+			return nil, fmt.Errorf("redis: invalid verbatim string %q", buf[:n])
+		}
+		return Verbatim{Format: string(buf[:3]), Text: string(buf[4:n])}, nil
+	case '~', '>':
+		n, err := parseAsciiInt(line[1:])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("redis: invalid aggregate length %q", line[1:])
+		}
+		arr, err := readArray(r, n)
+		if err != nil {
+			return nil, err
+		}
+		if line[0] == '~' {
+			return Set(arr), nil
+		}
+		return Push(arr), nil
+	case '%', '|':
+		n, err := parseAsciiInt(line[1:])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("redis: invalid map length %q", line[1:])
+		}
+		m, err := readMap(r, n)
+		if err != nil {
+			return nil, err
+		}
+		if line[0] == '%' {
+			return m, nil
+		}
+		// Attributes precede the reply they describe.
+		reply, err := ReadReply(r)
+		if err != nil {
+			return nil, err
 		}
-		return arr, nil
+		return Attribute{Attrs: m, Reply: reply}, nil
 	default:
 		return nil, fmt.Errorf("redis: unknown RESP type %q", line[0])
 	}
 }
 
+func readArray(r *bufio.Reader, n int64) ([]any, error) {
+	arr := make([]any, n)
+	for i := range arr {
+		v, err := ReadReply(r)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = v
+	}
+	return arr, nil
+}
+
+func readMap(r *bufio.Reader, n int64) (map[any]any, error) {
+	m := make(map[any]any, n)
+	for range n {
+		k, err := ReadReply(r)
+		if err != nil {
+			return nil, err
+		}
+		v, err := ReadReply(r)
+		if err != nil {
+			return nil, err
+		}
+		switch k.(type) {
+		case []any, Set, Push, map[any]any, Attribute:
+			return nil, fmt.Errorf("redis: unhashable map key %T", k)
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
 // readLine reads a line up to \r\n without allocating if it fits in bufio buffer.
 func readLine(r *bufio.Reader) ([]byte, error) {
 	line, isPrefix, err := r.ReadLine()
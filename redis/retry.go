@@ -0,0 +1,147 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+)
+
+// idempotentCommands are the commands that leave the same state when run
+// twice, on top of readOnlyCommands: retrying them after a connection error
+// is safe even if the first attempt reached the server.
+var idempotentCommands = map[string]bool{
+	"PING": true, "ECHO": true, "TIME": true, "DBSIZE": true, "INFO": true,
+	"SETEX": true, "PSETEX": true, "MSET": true, "DEL": true, "UNLINK": true,
+	"HSET": true, "HMSET": true, "HDEL": true,
+	"SADD": true, "SREM": true, "ZREM": true,
+	"PERSIST": true, "EXPIREAT": true, "PEXPIREAT": true,
+	"SCRIPT": true, // LOAD, EXISTS, FLUSH
+}
+
+// IsIdempotent reports whether cmd is a built-in command that is safe to
+// retry after a connection error: a read, or a write such as DEL or HSET
+// whose effect does not change when applied twice. SET and ZADD qualify
+// unless they carry options that make the reply depend on the previous
+// state (NX, XX, GET, INCR, ...). Scripts are not idempotent unless the
+// call is marked with WithIdempotent.
+func IsIdempotent(cmd []any) bool {
+	if len(cmd) == 0 {
+		return false
+	}
+	name, _ := cmd[0].(string)
+	name = strings.ToUpper(name)
+	switch name {
+	case "SET":
+		return len(cmd) >= 3 && !hasOption(cmd[3:], "NX", "XX", "GET")
+	case "ZADD":
+		return len(cmd) >= 2 && !hasOption(cmd[2:], "NX", "XX", "GT", "LT", "INCR")
+	}
+	return readOnlyCommands[name] || idempotentCommands[name]
+}
+
+// hasOption reports whether any string argument equals one of opts.
+func hasOption(args []any, opts ...string) bool {
+	for _, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			continue
+		}
+		for _, opt := range opts {
+			if strings.EqualFold(s, opt) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// idempotentKey marks a context whose command is safe to retry.
+type idempotentKey struct{}
+
+// WithIdempotent marks the command executed with ctx as safe to retry after
+// a connection error, e.g. an EVALSHA of a script that only sets values.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func isIdempotent(ctx context.Context, cmd []any) bool {
+	marked, _ := ctx.Value(idempotentKey{}).(bool)
+	return marked || IsIdempotent(cmd)
+}
+
+// DefaultShouldRetry is the retry policy used when Options.ShouldRetry is
+// nil. LOADING, TRYAGAIN, BUSY, MASTERDOWN and CLUSTERDOWN replies are
+// retried for every command, since the server rejected the command without
+// running it. Connection errors (EOF, reset, refused, ...) are retried for
+// idempotent commands only. Timeouts are never retried: the server may just
+// be slow.
+func DefaultShouldRetry(err error, cmd []any) bool {
+	return shouldRetry(err, IsIdempotent(cmd))
+}
+
+func shouldRetry(err error, idempotent bool) bool {
+	for _, target := range []error{ErrLoading, ErrTryAgain, ErrBusy, ErrMasterDown, ErrClusterDown} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	if !idempotent {
+		return false
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return !ne.Timeout()
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retry runs process, retrying the command with backoff while MaxRetries
+// and the retry policy permit.
+func (c *Client) retry(ctx context.Context, args []any) (any, error) {
+	maxRetries := c.opts.maxRetries()
+	for attempt := 0; ; attempt++ {
+		reply, err := c.process(ctx, args)
+		if err == nil || attempt >= maxRetries || !c.shouldRetry(ctx, err, args) {
+			return reply, err
+		}
+
+		t := time.NewTimer(c.opts.retryBackoff(attempt))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, err
+		case <-c.closedCh:
+			t.Stop()
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) shouldRetry(ctx context.Context, err error, args []any) bool {
+	if ctx.Err() != nil || c.closed.Load() {
+		return false
+	}
+	if c.opts.ShouldRetry != nil {
+		return c.opts.ShouldRetry(err, args)
+	}
+	return shouldRetry(err, isIdempotent(ctx, args))
+}
+
+// retryBackoff returns the wait before retry attempt+1: an exponential
+// backoff from MinRetryBackoff capped at MaxRetryBackoff, with jitter.
+func (o *Options) retryBackoff(attempt int) time.Duration {
+	lo, hi := o.minRetryBackoff(), o.maxRetryBackoff()
+	if lo == 0 || hi == 0 {
+		return 0
+	}
+	d := lo << min(attempt, 16)
+	if d > hi || d <= 0 {
+		d = hi
+	}
+	return d/2 + rand.N(d/2+1)
+}
@@ -0,0 +1,181 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+func TestIsIdempotent(t *testing.T) {
+	for _, tc := range []struct {
+		cmd  []any
+		want bool
+	}{
+		{[]any{"get", "k"}, true},
+		{[]any{"HSET", "h", "f", "v"}, true},
+		{[]any{"SET", "k", "v", "EX", 10}, true},
+		{[]any{"SET", "k", "v", "NX"}, false},
+		{[]any{"SET", "k", "v", "get"}, false},
+		{[]any{"ZADD", "z", 1, "m"}, true},
+		{[]any{"ZADD", "z", "INCR", 1, "m"}, false},
+		{[]any{"INCR", "n"}, false},
+		{[]any{"EVALSHA", "abc", 0}, false},
+		{nil, false},
+	} {
+		if got := redis.IsIdempotent(tc.cmd); got != tc.want {
+			t.Errorf("IsIdempotent(%v) = %v, want %v", tc.cmd, got, tc.want)
+		}
+	}
+}
+
+func TestDefaultShouldRetry(t *testing.T) {
+	get, incr := []any{"GET", "k"}, []any{"INCR", "n"}
+	for _, tc := range []struct {
+		err  error
+		cmd  []any
+		want bool
+	}{
+		{redis.RedisError("LOADING Redis is loading the dataset in memory"), incr, true},
+		{redis.RedisError("BUSY Redis is busy running a script"), incr, true},
+		{redis.RedisError("ERR wrong number of arguments"), get, false},
+		{io.EOF, get, true},
+		{io.EOF, incr, false},
+		{errors.New("custom dialer failed"), get, false},
+		{context.DeadlineExceeded, get, false},
+		{redis.ErrPoolTimeout, get, false},
+	} {
+		if got := redis.DefaultShouldRetry(tc.err, tc.cmd); got != tc.want {
+			t.Errorf("DefaultShouldRetry(%v, %v) = %v, want %v", tc.err, tc.cmd, got, tc.want)
+		}
+	}
+}
+
+// flakyServer replies LOADING to the first fails commands, then "a".
+func flakyServer(t *testing.T, fails int32) (*fakeServer, *atomic.Int32) {
+	var calls atomic.Int32
+	s := newFakeServer(t, func([]string) string {
+		if calls.Add(1) <= fails {
+			return "-LOADING Redis is loading the dataset in memory\r\n"
+		}
+		return bulk("a")
+	})
+	return s, &calls
+}
+
+func TestClient_Retry(t *testing.T) {
+	ctx := context.Background()
+
+	s, calls := flakyServer(t, 2)
+	c := redis.NewClient(&redis.Options{Addr: s.addr(), MinRetryBackoff: time.Millisecond})
+	defer c.Close()
+	if got, err := c.Do(ctx, "INCR", "n"); err != nil || got != "a" {
+		t.Fatalf("expected a after retries, got %v, %v", got, err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+
+	// Give up after MaxRetries.
+	s, calls = flakyServer(t, 10)
+	c2 := redis.NewClient(&redis.Options{Addr: s.addr(), MaxRetries: 2, MinRetryBackoff: -1})
+	defer c2.Close()
+	if _, err := c2.Do(ctx, "INCR", "n"); !errors.Is(err, redis.ErrLoading) {
+		t.Fatalf("expected ErrLoading, got %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+
+	// Disabled.
+	s, calls = flakyServer(t, 1)
+	c3 := redis.NewClient(&redis.Options{Addr: s.addr(), MaxRetries: -1})
+	defer c3.Close()
+	if _, err := c3.Do(ctx, "INCR", "n"); !errors.Is(err, redis.ErrLoading) {
+		t.Fatalf("expected ErrLoading, got %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected 1 attempt, got %d", n)
+	}
+}
+
+func TestClient_RetryBackoff(t *testing.T) {
+	s, _ := flakyServer(t, 3)
+	c := redis.NewClient(&redis.Options{
+		Addr:            s.addr(),
+		MinRetryBackoff: 10 * time.Millisecond,
+		MaxRetryBackoff: 20 * time.Millisecond,
+	})
+	defer c.Close()
+
+	// Backoffs of 10ms, 20ms and 20ms, each jittered down to half.
+	start := time.Now()
+	if _, err := c.Do(context.Background(), "GET", "k"); err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	if d := time.Since(start); d < 25*time.Millisecond || d > time.Second {
+		t.Fatalf("retried in %v, expected 25-50ms of backoff", d)
+	}
+
+	// The context ends the backoff early.
+	s, _ = flakyServer(t, 10)
+	c2 := redis.NewClient(&redis.Options{Addr: s.addr(), MinRetryBackoff: time.Hour, MaxRetryBackoff: time.Hour})
+	defer c2.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c2.Do(ctx, "GET", "k"); !errors.Is(err, redis.ErrLoading) {
+		t.Fatalf("expected ErrLoading, got %v", err)
+	}
+}
+
+func TestClient_RetryConnError(t *testing.T) {
+	var s *fakeServer
+	var calls atomic.Int32
+	s = newFakeServer(t, func([]string) string {
+		if calls.Add(1)%2 == 1 {
+			s.dropConns() // the command ran, but its reply is lost
+			return ""
+		}
+		return bulk("a")
+	})
+	c := redis.NewClient(&redis.Options{Addr: s.addr(), MinRetryBackoff: -1})
+	defer c.Close()
+	ctx := context.Background()
+
+	if got, err := c.Get(ctx, "k"); err != nil || got != "a" {
+		t.Fatalf("expected a after a retry, got %q, %v", got, err)
+	}
+	if _, err := c.Do(ctx, "INCR", "n"); err == nil {
+		t.Fatal("expected INCR not to be retried after a connection error")
+	}
+	if _, err := c.Do(ctx, "INCR", "n"); err != nil {
+		t.Fatalf("INCR: %v", err)
+	}
+	if got, err := c.Do(redis.WithIdempotent(ctx), "INCR", "n"); err != nil || got != "a" {
+		t.Fatalf("expected a marked INCR to be retried, got %v, %v", got, err)
+	}
+}
+
+func TestClient_ShouldRetry(t *testing.T) {
+	s, calls := flakyServer(t, 1)
+	var seen []any
+	c := redis.NewClient(&redis.Options{
+		Addr: s.addr(),
+		ShouldRetry: func(err error, cmd []any) bool {
+			seen = cmd
+			return false
+		},
+	})
+	defer c.Close()
+
+	if _, err := c.Do(context.Background(), "GET", "k"); !errors.Is(err, redis.ErrLoading) {
+		t.Fatalf("expected ErrLoading, got %v", err)
+	}
+	if n := calls.Load(); n != 1 || len(seen) != 2 || seen[0] != "GET" {
+		t.Fatalf("expected one attempt and ShouldRetry called with GET k, got %d and %v", n, seen)
+	}
+}
@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// ScanIterator walks the elements returned by SCAN, HSCAN, SSCAN or ZSCAN,
+// following the cursor until Redis returns 0. Pages are fetched lazily and
+// the context is checked before each page. HScan yields field and value
+// alternately; ZScan yields member and score alternately.
+//
+// As with SCAN itself, an element may be returned more than once and
+// elements added or removed during the iteration may or may not be seen.
+// A ScanIterator is not safe for concurrent use.
+type ScanIterator struct {
+	c    *Client
+	ctx  context.Context
+	name string
+	key  string // empty for SCAN
+	opts []any  // MATCH / COUNT / TYPE
+
+	cursor  string
+	started bool
+	page    []string
+	pos     int
+	val     string
+	err     error
+}
+
+// Scan iterates the keys of the current database. match ("" for all), count
+// (0 for the server default) and typ ("" for any type) map to the MATCH,
+// COUNT and TYPE options.
+func (c *Client) Scan(ctx context.Context, match string, count int64, typ string) *ScanIterator {
+	it := c.newScanIterator(ctx, "SCAN", "", match, count)
+	if typ != "" {
+		it.opts = append(it.opts, "TYPE", typ)
+	}
+	return it
+}
+
+// HScan iterates the fields and values of a hash.
+func (c *Client) HScan(ctx context.Context, key, match string, count int64) *ScanIterator {
+	return c.newScanIterator(ctx, "HSCAN", key, match, count)
+}
+
+// SScan iterates the members of a set.
+func (c *Client) SScan(ctx context.Context, key, match string, count int64) *ScanIterator {
+	return c.newScanIterator(ctx, "SSCAN", key, match, count)
+}
+
+// ZScan iterates the members and scores of a sorted set.
+func (c *Client) ZScan(ctx context.Context, key, match string, count int64) *ScanIterator {
+	return c.newScanIterator(ctx, "ZSCAN", key, match, count)
+}
+
+func (c *Client) newScanIterator(ctx context.Context, name, key, match string, count int64) *ScanIterator {
+	it := &ScanIterator{c: c, ctx: ctx, name: name, key: key, cursor: "0"}
+	if match != "" {
+		it.opts = append(it.opts, "MATCH", match)
+	}
+	if count > 0 {
+		it.opts = append(it.opts, "COUNT", count)
+	}
+	return it
+}
+
+// Next advances to the next element, fetching the next page when needed.
+// It returns false when the iteration is complete or an error occurred.
+func (it *ScanIterator) Next() bool {
+	for it.pos >= len(it.page) {
+		if it.err != nil || (it.started && it.cursor == "0") {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		if err := it.fetch(); err != nil {
+			it.err = err
+			return false
+		}
+	}
+	it.val = it.page[it.pos]
+	it.pos++
+	return true
+}
+
+// Val returns the current element.
+func (it *ScanIterator) Val() string { return it.val }
+
+// Err returns the error that stopped the iteration, if any.
+func (it *ScanIterator) Err() error { return it.err }
+
+// All returns the remaining elements as a sequence for use with range.
+// Check Err after the loop.
+func (it *ScanIterator) All() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for it.Next() {
+			if !yield(it.val) {
+				return
+			}
+		}
+	}
+}
+
+func (it *ScanIterator) fetch() error {
+	args := make([]any, 0, 3+len(it.opts))
+	args = append(args, it.name)
+	if it.key != "" {
+		args = append(args, it.key)
+	}
+	args = append(args, it.cursor)
+	args = append(args, it.opts...)
+
+	if it.key == "" && it.c.cluster != nil {
+		// A SCAN cursor is only valid on the node that returned it: pin
+		// the iteration to the node of the first page.
+		node, err := it.c.cluster.slotNode(it.ctx, -1)
+		if err != nil {
+			return err
+		}
+		it.c = node
+	}
+
+	reply, err := it.c.Do(it.ctx, args...)
+	if err != nil {
+		return err
+	}
+	arr, ok := reply.([]any)
+	if !ok || len(arr) != 2 {
+		return fmt.Errorf("redis: unexpected reply %T from %s", reply, it.name)
+	}
+	cursor, ok := arr[0].(string)
+	if !ok {
+		return fmt.Errorf("redis: unexpected cursor %T from %s", arr[0], it.name)
+	}
+	page, err := stringSlice(arr[1], it.name)
+	if err != nil {
+		return err
+	}
+	it.cursor, it.page, it.pos, it.started = cursor, page, 0, true
+	return nil
+}
@@ -0,0 +1,125 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+func collect(t *testing.T, it *redis.ScanIterator) []string {
+	t.Helper()
+	var vals []string
+	for it.Next() {
+		vals = append(vals, it.Val())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	return vals
+}
+
+func TestClient_Scan(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 50; i++ {
+		if err := c.Set(ctx, fmt.Sprintf("user:%02d", i), "x", 0); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+	c.Set(ctx, "other", "x", 0)
+	c.SAdd(ctx, "user:set", "a")
+
+	keys := collect(t, c.Scan(ctx, "user:*", 10, ""))
+	slices.Sort(keys)
+	keys = slices.Compact(keys) // SCAN may return a key more than once
+	if len(keys) != 51 {
+		t.Fatalf("expected 51 keys, got %d", len(keys))
+	}
+
+	keys = collect(t, c.Scan(ctx, "user:*", 10, "set"))
+	if len(keys) != 1 || keys[0] != "user:set" {
+		t.Fatalf("TYPE set: unexpected %v", keys)
+	}
+
+	var all []string
+	it := c.Scan(ctx, "", 0, "")
+	for k := range it.All() {
+		all = append(all, k)
+	}
+	if it.Err() != nil || len(all) < 52 {
+		t.Fatalf("All: got %d keys, %v", len(all), it.Err())
+	}
+}
+
+func TestClient_HScanSScanZScan(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		c.HSet(ctx, "h", fmt.Sprintf("f%d", i), fmt.Sprintf("v%d", i))
+		c.SAdd(ctx, "s", fmt.Sprintf("m%d", i))
+		c.ZAdd(ctx, "z", redis.Z{Member: fmt.Sprintf("m%d", i), Score: float64(i)})
+	}
+
+	pairs := collect(t, c.HScan(ctx, "h", "f1*", 5))
+	got := map[string]string{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		got[pairs[i]] = pairs[i+1]
+	}
+	if len(got) != 11 || got["f15"] != "v15" {
+		t.Fatalf("HScan: unexpected %v", got)
+	}
+
+	members := collect(t, c.SScan(ctx, "s", "", 5))
+	slices.Sort(members)
+	if len(slices.Compact(members)) != 20 {
+		t.Fatalf("SScan: unexpected %v", members)
+	}
+
+	pairs = collect(t, c.ZScan(ctx, "z", "m1", 0))
+	if len(pairs) != 2 || pairs[0] != "m1" || pairs[1] != "1" {
+		t.Fatalf("ZScan: unexpected %v", pairs)
+	}
+
+	if vals := collect(t, c.SScan(ctx, "missing", "", 0)); len(vals) != 0 {
+		t.Fatalf("SScan missing key: unexpected %v", vals)
+	}
+}
+
+func TestClient_ScanContextCancel(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Set(ctx, "k", "x", 0)
+
+	it := c.Scan(ctx, "", 5, "")
+	cancel() // checked before each page, including the first
+	if it.Next() {
+		t.Fatal("expected no elements after cancel")
+	}
+	if !errors.Is(it.Err(), context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", it.Err())
+	}
+}
+
+func TestClient_ScanWrongType(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	c.Set(ctx, "str", "x", 0)
+	it := c.HScan(ctx, "str", "", 0)
+	if it.Next() {
+		t.Fatal("expected no elements")
+	}
+	if !errors.Is(it.Err(), redis.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType, got %v", it.Err())
+	}
+}
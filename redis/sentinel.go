@@ -0,0 +1,368 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FailoverOptions configures a client for a master managed by Redis Sentinel.
+type FailoverOptions struct {
+	// MasterName is the name of the master monitored by the Sentinels.
+	MasterName string
+
+	// SentinelAddrs are the "host:port" addresses of the Sentinels.
+	SentinelAddrs []string
+
+	// SentinelPassword authenticates to the Sentinels, if they require it.
+	SentinelPassword string
+
+	// ReadFromReplicas routes read-only commands sent through Do and the
+	// typed helpers to a replica. Their replies may lag behind the master.
+	// When the replica cannot be reached, the command is read from the
+	// master and Sentinel is asked for another replica on the next dial.
+	// Pipelines, transactions and scripts always go to the master.
+	ReadFromReplicas bool
+
+	// Options configures the connections to the master and replicas.
+	// Addr is ignored. TLSConfig and Dialer also apply to the Sentinels.
+	Options
+}
+
+// NewFailoverClient creates a client that finds the master through Sentinel
+// with SENTINEL get-master-addr-by-name and follows +switch-master events:
+// on failover the pool is re-pointed to the new master and connections to
+// the old one are closed.
+func NewFailoverClient(opts *FailoverOptions) *Client {
+	f := &sentinelFailover{
+		opts:      opts,
+		sentinels: append([]string(nil), opts.SentinelAddrs...),
+		exit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	o := opts.Options
+	o.Addr = ""
+	c := newClient(&o)
+	c.resolve = f.masterAddr
+	c.failover = f
+	f.master = c
+
+	if opts.ReadFromReplicas {
+		ro := opts.Options
+		ro.Addr = ""
+		r := newClient(&ro)
+		r.resolve = f.replicaAddr
+		r.parent = c
+		c.replica = r
+		f.replica = r
+		r.start()
+	}
+	c.start()
+
+	go f.watch()
+	return c
+}
+
+// sentinelFailover resolves the master and replicas through Sentinel and
+// watches for failovers.
+type sentinelFailover struct {
+	opts    *FailoverOptions
+	master  *Client
+	replica *Client // nil unless ReadFromReplicas
+
+	mu        sync.Mutex
+	sentinels []string // the last working Sentinel first
+	cn        *conn    // current +switch-master subscription
+
+	exit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// dialSentinel connects to one Sentinel.
+func (f *sentinelFailover) dialSentinel(ctx context.Context, addr string) (*Client, *conn, error) {
+	sc := &Client{
+		opts: &Options{
+			Password:     f.opts.SentinelPassword,
+			DialTimeout:  f.opts.DialTimeout,
+			ReadTimeout:  f.opts.ReadTimeout,
+			WriteTimeout: f.opts.WriteTimeout,
+			TLSConfig:    f.opts.TLSConfig,
+			Dialer:       f.opts.Dialer,
+		},
+		addr: addr,
+	}
+	cn, err := sc.dialConn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sc, cn, nil
+}
+
+// query runs fn against the Sentinels in turn until one succeeds, moving
+// that Sentinel to the front of the list.
+func (f *sentinelFailover) query(ctx context.Context, fn func(sc *Client, cn *conn) error) error {
+	f.mu.Lock()
+	sentinels := append([]string(nil), f.sentinels...)
+	f.mu.Unlock()
+
+	var lastErr error
+	for i, addr := range sentinels {
+		sc, cn, err := f.dialSentinel(ctx, addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		err = fn(sc, cn)
+		cn.nc.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if i > 0 {
+			f.promote(addr)
+		}
+		return nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no sentinel addresses")
+	}
+	return fmt.Errorf("redis: sentinel: %w", lastErr)
+}
+
+func (f *sentinelFailover) promote(addr string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, a := range f.sentinels {
+		if a == addr {
+			copy(f.sentinels[1:i+1], f.sentinels[:i])
+			f.sentinels[0] = addr
+			return
+		}
+	}
+}
+
+// masterAddr asks the Sentinels for the current master address.
+func (f *sentinelFailover) masterAddr(ctx context.Context) (string, error) {
+	var addr string
+	err := f.query(ctx, func(sc *Client, cn *conn) error {
+		var err error
+		addr, err = sentinelMasterAddr(ctx, sc, cn, f.opts.MasterName)
+		return err
+	})
+	return addr, err
+}
+
+// replicaAddr picks a random healthy replica, falling back to the master
+// when none is available.
+func (f *sentinelFailover) replicaAddr(ctx context.Context) (string, error) {
+	var addrs []string
+	err := f.query(ctx, func(sc *Client, cn *conn) error {
+		var err error
+		addrs, err = sentinelReplicaAddrs(ctx, sc, cn, f.opts.MasterName)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return f.masterAddr(ctx)
+	}
+	return addrs[rand.IntN(len(addrs))], nil
+}
+
+func sentinelMasterAddr(ctx context.Context, sc *Client, cn *conn, name string) (string, error) {
+	reply, err := sc.execOn(ctx, cn, "SENTINEL", "get-master-addr-by-name", name)
+	if err != nil {
+		return "", err
+	}
+	arr, ok := reply.([]any)
+	if !ok || len(arr) != 2 {
+		return "", fmt.Errorf("master %q is unknown", name)
+	}
+	host, _ := arr[0].(string)
+	port, _ := arr[1].(string)
+	return net.JoinHostPort(host, port), nil
+}
+
+func sentinelReplicaAddrs(ctx context.Context, sc *Client, cn *conn, name string) ([]string, error) {
+	reply, err := sc.execOn(ctx, cn, "SENTINEL", "replicas", name)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T from SENTINEL replicas", reply)
+	}
+	var addrs []string
+	for _, v := range arr {
+		m, err := fieldMap(v, "SENTINEL replicas")
+		if err != nil {
+			return nil, err
+		}
+		flags, _ := m["flags"].(string)
+		if strings.Contains(flags, "s_down") || strings.Contains(flags, "o_down") || strings.Contains(flags, "disconnected") {
+			continue
+		}
+		host, _ := m["ip"].(string)
+		port, _ := m["port"].(string)
+		if host != "" && port != "" {
+			addrs = append(addrs, net.JoinHostPort(host, port))
+		}
+	}
+	return addrs, nil
+}
+
+// watch keeps a +switch-master subscription on one of the Sentinels,
+// moving to the next one when it fails.
+func (f *sentinelFailover) watch() {
+	defer close(f.done)
+	backoff := pubsubMinBackoff
+	for {
+		if f.watchOnce() {
+			backoff = pubsubMinBackoff
+		}
+		select {
+		case <-f.exit:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, pubsubMaxBackoff)
+	}
+}
+
+// watchOnce subscribes to +switch-master on the first reachable Sentinel,
+// re-resolves the master (a switch may have been missed while disconnected)
+// and processes events until the connection fails. It reports whether a
+// subscription was established.
+func (f *sentinelFailover) watchOnce() bool {
+	f.mu.Lock()
+	sentinels := append([]string(nil), f.sentinels...)
+	f.mu.Unlock()
+
+	for _, addr := range sentinels {
+		select {
+		case <-f.exit:
+			return false
+		default:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), f.master.opts.dialTimeout())
+		sc, cn, err := f.dialSentinel(ctx, addr)
+		if err != nil {
+			cancel()
+			continue
+		}
+		master, err := sentinelMasterAddr(ctx, sc, cn, f.opts.MasterName)
+		cancel()
+		if err == nil {
+			err = WriteCommand(cn.nc, "SUBSCRIBE", "+switch-master")
+		}
+		if err != nil {
+			cn.nc.Close()
+			continue
+		}
+
+		f.mu.Lock()
+		select {
+		case <-f.exit:
+			f.mu.Unlock()
+			cn.nc.Close()
+			return false
+		default:
+		}
+		f.cn = cn
+		f.mu.Unlock()
+		f.promote(addr)
+		f.switchMaster(master)
+
+		f.receive(cn)
+
+		f.mu.Lock()
+		f.cn = nil
+		f.mu.Unlock()
+		cn.nc.Close()
+		return true
+	}
+	return false
+}
+
+// receive handles +switch-master events until cn fails. The connection is
+// pinged when idle so that a dead Sentinel is noticed.
+func (f *sentinelFailover) receive(cn *conn) {
+	pinged := false
+	for {
+		cn.nc.SetReadDeadline(time.Now().Add(pubsubPingInterval))
+		reply, err := ReadReply(cn.rd)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() && !pinged {
+				pinged = true
+				cn.nc.SetWriteDeadline(time.Now().Add(f.master.opts.dialTimeout()))
+				if WriteCommand(cn.nc, "PING") == nil {
+					continue
+				}
+			}
+			return
+		}
+		pinged = false
+
+		msg := parseMessage(reply)
+		if msg == nil || msg.Channel != "+switch-master" {
+			continue
+		}
+		// <master name> <old ip> <old port> <new ip> <new port>
+		parts := strings.Fields(msg.Payload)
+		if len(parts) != 5 || parts[0] != f.opts.MasterName {
+			continue
+		}
+		f.switchMaster(net.JoinHostPort(parts[3], parts[4]))
+	}
+}
+
+// switchMaster re-points the master pool and makes the replica pool
+// pick a replica of the new master on its next dial.
+func (f *sentinelFailover) switchMaster(addr string) {
+	if f.master.setAddr(addr) && f.replica != nil {
+		f.replica.setAddr("")
+	}
+}
+
+func (f *sentinelFailover) close() {
+	f.closeOnce.Do(func() {
+		f.mu.Lock()
+		close(f.exit)
+		if f.cn != nil {
+			f.cn.nc.Close()
+		}
+		f.mu.Unlock()
+		<-f.done
+	})
+}
+
+// readOnlyCommands are the commands routed to replicas by ReadFromReplicas.
+var readOnlyCommands = map[string]bool{
+	"GET": true, "MGET": true, "STRLEN": true, "GETRANGE": true,
+	"EXISTS": true, "TTL": true, "PTTL": true, "TYPE": true,
+	"HGET": true, "HMGET": true, "HGETALL": true, "HEXISTS": true, "HLEN": true, "HKEYS": true, "HVALS": true,
+	"LLEN": true, "LRANGE": true, "LINDEX": true,
+	"SMEMBERS": true, "SISMEMBER": true, "SMISMEMBER": true, "SCARD": true,
+	"ZSCORE": true, "ZMSCORE": true, "ZRANK": true, "ZREVRANK": true, "ZRANGE": true,
+	"ZCARD": true, "ZCOUNT": true, "ZUNION": true, "ZINTER": true, "ZDIFF": true,
+	"XRANGE": true, "XREVRANGE": true, "XLEN": true, "XREAD": true,
+	"SCAN": true, "HSCAN": true, "SSCAN": true, "ZSCAN": true,
+	"EVAL_RO": true, "EVALSHA_RO": true,
+}
+
+func isReadOnly(args []any) bool {
+	if len(args) == 0 {
+		return false
+	}
+	name, _ := args[0].(string)
+	return readOnlyCommands[strings.ToUpper(name)]
+}
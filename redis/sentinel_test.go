@@ -0,0 +1,356 @@
+package redis_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+// fakeServer is a minimal RESP server. handle returns the raw reply for a
+// command; SUBSCRIBE is handled by the server so that publish can push
+// messages to subscribers.
+type fakeServer struct {
+	t      *testing.T
+	ln     net.Listener
+	handle func(args []string) string
+
+	mu    sync.Mutex
+	conns []net.Conn
+	subs  []net.Conn
+}
+
+func newFakeServer(t *testing.T, handle func(args []string) string) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	return startFakeServer(t, ln, handle)
+}
+
+func startFakeServer(t *testing.T, ln net.Listener, handle func(args []string) string) *fakeServer {
+	s := &fakeServer{t: t, ln: ln, handle: handle}
+	go s.serve()
+	t.Cleanup(s.close)
+	return s
+}
+
+func (s *fakeServer) addr() string { return s.ln.Addr().String() }
+
+func (s *fakeServer) hostPort() (string, string) {
+	host, port, _ := net.SplitHostPort(s.addr())
+	return host, port
+}
+
+func (s *fakeServer) serve() {
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, nc)
+		s.mu.Unlock()
+		go s.serveConn(nc)
+	}
+}
+
+func (s *fakeServer) serveConn(nc net.Conn) {
+	defer nc.Close()
+	rd := bufio.NewReader(nc)
+	for {
+		reply, err := redis.ReadReply(rd)
+		if err != nil {
+			return
+		}
+		arr, _ := reply.([]any)
+		args := make([]string, len(arr))
+		for i, v := range arr {
+			args[i], _ = v.(string)
+		}
+		if len(args) == 0 {
+			return
+		}
+		var out string
+		switch strings.ToUpper(args[0]) {
+		case "SUBSCRIBE":
+			s.mu.Lock()
+			s.subs = append(s.subs, nc)
+			s.mu.Unlock()
+			out = "*3\r\n" + bulk("subscribe") + bulk(args[1]) + ":1\r\n"
+		case "PING":
+			out = "+PONG\r\n"
+		default:
+			out = s.handle(args)
+		}
+		s.mu.Lock()
+		_, err = nc.Write([]byte(out))
+		s.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// publish pushes a message to every subscriber.
+func (s *fakeServer) publish(channel, payload string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, nc := range s.subs {
+		nc.Write([]byte("*3\r\n" + bulk("message") + bulk(channel) + bulk(payload)))
+	}
+}
+
+func (s *fakeServer) numSubs() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs)
+}
+
+func (s *fakeServer) close() {
+	s.ln.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, nc := range s.conns {
+		nc.Close()
+	}
+}
+
+// dropConns closes the open connections but keeps accepting new ones, like
+// a NAT forgetting idle sessions.
+func (s *fakeServer) dropConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, nc := range s.conns {
+		nc.Close()
+	}
+	s.conns = nil
+}
+
+func bulk(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+
+// nameServer replies to every command with its name.
+func nameServer(t *testing.T, name string) *fakeServer {
+	return newFakeServer(t, func([]string) string { return bulk(name) })
+}
+
+// fakeSentinel monitors "mymaster" and reports the given master and replicas.
+type fakeSentinel struct {
+	*fakeServer
+	mu       sync.Mutex
+	master   *fakeServer
+	replicas []*fakeServer
+}
+
+func newFakeSentinel(t *testing.T, master *fakeServer, replicas ...*fakeServer) *fakeSentinel {
+	fs := &fakeSentinel{master: master, replicas: replicas}
+	fs.fakeServer = newFakeServer(t, fs.handle)
+	return fs
+}
+
+func (fs *fakeSentinel) handle(args []string) string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if len(args) != 3 || strings.ToUpper(args[0]) != "SENTINEL" {
+		return "-ERR unknown command\r\n"
+	}
+	if args[2] != "mymaster" {
+		return "*-1\r\n"
+	}
+	switch args[1] {
+	case "get-master-addr-by-name":
+		host, port := fs.master.hostPort()
+		return "*2\r\n" + bulk(host) + bulk(port)
+	case "replicas":
+		out := fmt.Sprintf("*%d\r\n", len(fs.replicas))
+		for _, r := range fs.replicas {
+			host, port := r.hostPort()
+			out += "*6\r\n" + bulk("ip") + bulk(host) + bulk("port") + bulk(port) + bulk("flags") + bulk("slave")
+		}
+		return out
+	}
+	return "-ERR unknown subcommand\r\n"
+}
+
+// failover switches the master and announces it with +switch-master.
+func (fs *fakeSentinel) failover(to *fakeServer) {
+	fs.mu.Lock()
+	oldHost, oldPort := fs.master.hostPort()
+	fs.master = to
+	fs.mu.Unlock()
+	newHost, newPort := to.hostPort()
+	fs.publish("+switch-master", strings.Join([]string{"mymaster", oldHost, oldPort, newHost, newPort}, " "))
+}
+
+func whoami(t *testing.T, c *redis.Client, args ...any) string {
+	t.Helper()
+	reply, err := c.Do(context.Background(), args...)
+	if err != nil {
+		t.Fatalf("Do %v: %v", args, err)
+	}
+	s, _ := reply.(string)
+	return s
+}
+
+func TestFailoverClient_SwitchMaster(t *testing.T) {
+	a, b := nameServer(t, "a"), nameServer(t, "b")
+	sentinel := newFakeSentinel(t, a)
+
+	c := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:    "mymaster",
+		SentinelAddrs: []string{"127.0.0.1:1", sentinel.addr()}, // the first is down
+		Options:       redis.Options{DialTimeout: time.Second},
+	})
+	defer c.Close()
+
+	if got := whoami(t, c, "WHO"); got != "a" {
+		t.Fatalf("expected master a, got %q", got)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for sentinel.numSubs() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for +switch-master subscription")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sentinel.failover(b)
+	deadline = time.Now().Add(2 * time.Second)
+	for whoami(t, c, "WHO") != "b" {
+		if time.Now().After(deadline) {
+			t.Fatal("client did not switch to the new master")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s := c.PoolStats(); s.Active != 1 || s.Idle != 1 {
+		t.Fatalf("expected only the new master connection, got %+v", s)
+	}
+}
+
+func TestFailoverClient_ReadFromReplicas(t *testing.T) {
+	master, replica := nameServer(t, "master"), nameServer(t, "replica")
+	sentinel := newFakeSentinel(t, master, replica)
+
+	c := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:       "mymaster",
+		SentinelAddrs:    []string{sentinel.addr()},
+		ReadFromReplicas: true,
+	})
+	defer c.Close()
+
+	if got := whoami(t, c, "GET", "k"); got != "replica" {
+		t.Fatalf("GET: expected replica, got %q", got)
+	}
+	if got := whoami(t, c, "hgetall", "k"); got != "replica" {
+		t.Fatalf("HGETALL: expected replica, got %q", got)
+	}
+	if got := whoami(t, c, "SET", "k", "v"); got != "master" {
+		t.Fatalf("SET: expected master, got %q", got)
+	}
+	if got := whoami(t, c, "EVALSHA", "sha", 0); got != "master" {
+		t.Fatalf("EVALSHA: expected master, got %q", got)
+	}
+}
+
+func TestFailoverClient_ReplicaDown(t *testing.T) {
+	master, r1, r2 := nameServer(t, "master"), nameServer(t, "r1"), nameServer(t, "r2")
+	sentinel := newFakeSentinel(t, master, r1)
+
+	c := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:       "mymaster",
+		SentinelAddrs:    []string{sentinel.addr()},
+		ReadFromReplicas: true,
+		Options:          redis.Options{MaxRetries: -1},
+	})
+	defer c.Close()
+
+	if got := whoami(t, c, "GET", "k"); got != "r1" {
+		t.Fatalf("expected r1, got %q", got)
+	}
+
+	// r1 goes down without a master failover.
+	r1.close()
+	sentinel.mu.Lock()
+	sentinel.replicas = []*fakeServer{r2}
+	sentinel.mu.Unlock()
+
+	if got := whoami(t, c, "GET", "k"); got != "master" {
+		t.Fatalf("expected the read to fall back to the master, got %q", got)
+	}
+	if got := whoami(t, c, "GET", "k"); got != "r2" {
+		t.Fatalf("expected the next read on r2, got %q", got)
+	}
+}
+
+func TestFailoverClient_UnknownMaster(t *testing.T) {
+	sentinel := newFakeSentinel(t, nameServer(t, "a"))
+
+	c := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:    "other",
+		SentinelAddrs: []string{sentinel.addr()},
+	})
+	defer c.Close()
+
+	if _, err := c.Do(context.Background(), "PING"); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Fatalf("expected unknown master error, got %v", err)
+	}
+}
+
+func TestFailoverClient_SwitchMasterWaiter(t *testing.T) {
+	release := make(chan struct{})
+	a := newFakeServer(t, func(args []string) string {
+		if args[0] == "SLOW" {
+			<-release
+		}
+		return bulk("a")
+	})
+	b := nameServer(t, "b")
+	sentinel := newFakeSentinel(t, a)
+
+	c := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:    "mymaster",
+		SentinelAddrs: []string{sentinel.addr()},
+		Options:       redis.Options{PoolSize: 1},
+	})
+	defer c.Close()
+	whoami(t, c, "WHO")
+	deadline := time.Now().Add(2 * time.Second)
+	for sentinel.numSubs() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for +switch-master subscription")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The only connection is busy on a, and a second command waits for it.
+	go c.Do(context.Background(), "SLOW")
+	for c.PoolStats().Idle != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for SLOW")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	got := make(chan string, 1)
+	go func() { got <- whoami(t, c, "WHO") }()
+	for c.PoolStats().Waiters == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the waiter")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	sentinel.failover(b)
+	time.Sleep(100 * time.Millisecond) // let the client switch
+	close(release)
+	if who := <-got; who != "b" {
+		t.Fatalf("expected the waiter to get a connection to b, got %q", who)
+	}
+}
@@ -0,0 +1,228 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the pool gauges, cumulative pool counters and
+// latency histograms of a client. For Sentinel and Cluster clients the pool
+// figures are summed over the master, replica and node pools.
+type Stats struct {
+	PoolStats
+
+	Hits        uint64 // idle connection reused
+	Misses      uint64 // no idle connection: dialed or waited
+	Dials       uint64 // connections dialed, including failed attempts
+	DialErrors  uint64 // failed dials, including AUTH/SELECT/OnConnect errors
+	Timeouts    uint64 // waits for a connection ended by PoolTimeout or the context deadline
+	WaitCancels uint64 // waits for a connection ended by context cancellation
+	StaleConns  uint64 // closed for IdleTimeout or MaxConnAge
+	ErrorConns  uint64 // closed after an I/O error
+
+	// Circuit is the state of the circuit breaker, CircuitClosed unless
+	// Options.CircuitBreaker is set. CircuitRejects counts the commands and
+	// pipelines it failed with ErrCircuitOpen.
+	Circuit        CircuitState
+	CircuitRejects uint64
+
+	// WaitDuration is the time spent in the waiter queue when the pool
+	// was exhausted.
+	WaitDuration Histogram
+
+	// Commands holds the latency of Do (and every typed helper) by upper-cased
+	// command name, and of pipelines under "PIPELINE".
+	Commands map[string]Histogram
+}
+
+// Histogram is a latency distribution.
+type Histogram struct {
+	// Bounds are the bucket upper bounds, from 500µs to 10s.
+	Bounds []time.Duration
+	// Counts[i] is the number of observations in (Bounds[i-1], Bounds[i]];
+	// the extra last element counts those above every bound.
+	Counts []uint64
+	Count  uint64
+	Sum    time.Duration
+}
+
+// clientStats holds the counters of one client.
+type clientStats struct {
+	hits, misses, dials, dialErrors  atomic.Uint64
+	timeouts, staleConns, errorConns atomic.Uint64
+	waitCancels                      atomic.Uint64
+
+	wait     histogram
+	commands sync.Map // command name -> *histogram; recorded on the root client only
+}
+
+type histogram struct {
+	counts [len(latencyBounds) + 1]atomic.Uint64
+	sum    atomic.Int64
+}
+
+// latencyBounds are the bucket upper bounds of every histogram.
+var latencyBounds = [...]time.Duration{
+	500 * time.Microsecond, time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond,
+	250 * time.Millisecond, 500 * time.Millisecond, time.Second, 2500 * time.Millisecond,
+	5 * time.Second, 10 * time.Second,
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i, _ := slices.BinarySearch(latencyBounds[:], d)
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+func (h *histogram) addTo(out *Histogram) {
+	if out.Counts == nil {
+		out.Bounds = slices.Clone(latencyBounds[:])
+		out.Counts = make([]uint64, len(h.counts))
+	}
+	for i := range h.counts {
+		n := h.counts[i].Load()
+		out.Counts[i] += n
+		out.Count += n
+	}
+	out.Sum += time.Duration(h.sum.Load())
+}
+
+func (s *clientStats) observeCommand(name string, d time.Duration) {
+	h, ok := s.commands.Load(name)
+	if !ok {
+		h, _ = s.commands.LoadOrStore(name, new(histogram))
+	}
+	h.(*histogram).observe(d)
+}
+
+// Stats returns a snapshot of the client statistics.
+func (c *Client) Stats() Stats {
+	var st Stats
+	for _, cl := range c.pools() {
+		ps := cl.PoolStats()
+		st.Active += ps.Active
+		st.Idle += ps.Idle
+		st.Waiters += ps.Waiters
+
+		s := &cl.stats
+		st.Hits += s.hits.Load()
+		st.Misses += s.misses.Load()
+		st.Dials += s.dials.Load()
+		st.DialErrors += s.dialErrors.Load()
+		st.Timeouts += s.timeouts.Load()
+		st.WaitCancels += s.waitCancels.Load()
+		st.StaleConns += s.staleConns.Load()
+		st.ErrorConns += s.errorConns.Load()
+		s.wait.addTo(&st.WaitDuration)
+	}
+
+	if b := c.root().breaker; b != nil {
+		st.Circuit = b.currentState()
+		st.CircuitRejects = b.rejected.Load()
+	}
+
+	st.Commands = make(map[string]Histogram)
+	c.root().stats.commands.Range(func(k, v any) bool {
+		var h Histogram
+		v.(*histogram).addTo(&h)
+		st.Commands[k.(string)] = h
+		return true
+	})
+	return st
+}
+
+// pools returns c and the replica and cluster node clients it routes to.
+func (c *Client) pools() []*Client {
+	clients := []*Client{c}
+	if c.replica != nil {
+		clients = append(clients, c.replica)
+	}
+	if cs := c.cluster; cs != nil {
+		cs.mu.RLock()
+		for _, node := range cs.nodes {
+			clients = append(clients, node)
+		}
+		cs.mu.RUnlock()
+	}
+	return clients
+}
+
+// WritePrometheus writes the statistics in the Prometheus text exposition
+// format, with metric names prefixed by namespace ("redis" if empty). Serve
+// it from a metrics endpoint:
+//
+//	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+//		st := client.Stats()
+//		st.WritePrometheus(w, "myapp_redis")
+//	})
+func (s *Stats) WritePrometheus(w io.Writer, namespace string) error {
+	if namespace == "" {
+		namespace = "redis"
+	}
+	bw := bufio.NewWriter(w)
+	metric := func(name, typ, help string) string {
+		name = namespace + "_" + name
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		return name
+	}
+	value := func(name, typ, help string, v uint64) {
+		fmt.Fprintf(bw, "%s %d\n", metric(name, typ, help), v)
+	}
+
+	value("pool_connections", "gauge", "Open connections, idle and in use.", uint64(s.Active))
+	value("pool_idle_connections", "gauge", "Idle connections in the pool.", uint64(s.Idle))
+	value("pool_waiters", "gauge", "Goroutines waiting for a connection.", uint64(s.Waiters))
+	value("pool_hits_total", "counter", "Idle connections reused.", s.Hits)
+	value("pool_misses_total", "counter", "Connection requests that found no idle connection.", s.Misses)
+	value("pool_dials_total", "counter", "Connections dialed.", s.Dials)
+	value("pool_dial_errors_total", "counter", "Failed dials.", s.DialErrors)
+	value("pool_timeouts_total", "counter", "Waits for a connection that timed out.", s.Timeouts)
+	value("pool_wait_cancels_total", "counter", "Waits for a connection cancelled by the caller.", s.WaitCancels)
+	value("pool_stale_connections_total", "counter", "Connections closed for idle timeout or max age.", s.StaleConns)
+	value("pool_error_connections_total", "counter", "Connections closed after an I/O error.", s.ErrorConns)
+	value("circuit_state", "gauge", "Circuit breaker state: 0 closed, 1 open, 2 half-open.", uint64(s.Circuit))
+	value("circuit_rejects_total", "counter", "Commands failed by the open circuit breaker.", s.CircuitRejects)
+
+	name := metric("pool_wait_duration_seconds", "histogram", "Time spent waiting for a connection.")
+	writeHistogram(bw, name, "", &s.WaitDuration)
+
+	name = metric("command_duration_seconds", "histogram", "Command latency by command name.")
+	cmds := make([]string, 0, len(s.Commands))
+	for cmd := range s.Commands {
+		cmds = append(cmds, cmd)
+	}
+	slices.Sort(cmds)
+	for _, cmd := range cmds {
+		h := s.Commands[cmd]
+		writeHistogram(bw, name, "command="+strconv.Quote(cmd), &h)
+	}
+	return bw.Flush()
+}
+
+// writeHistogram writes the cumulative buckets, sum and count of h, with an
+// optional extra label pair such as command="GET".
+func writeHistogram(w io.Writer, name, label string, h *Histogram) {
+	bucketLabel, seriesLabel := "", ""
+	if label != "" {
+		bucketLabel, seriesLabel = label+",", "{"+label+"}"
+	}
+	var cum uint64
+	for i, bound := range h.Bounds {
+		cum += h.Counts[i]
+		fmt.Fprintf(w, "%s_bucket{%sle=%q} %d\n", name, bucketLabel, seconds(bound), cum)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, bucketLabel, h.Count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, seriesLabel, seconds(h.Sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, seriesLabel, h.Count)
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
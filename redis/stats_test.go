@@ -0,0 +1,146 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+func TestClient_Stats(t *testing.T) {
+	s := newFakeServer(t, func(args []string) string {
+		if args[0] == "HANG" {
+			return "" // never replies
+		}
+		return bulk("a")
+	})
+	c := redis.NewClient(&redis.Options{Addr: s.addr(), PoolSize: 1, ReadTimeout: 50 * time.Millisecond})
+	defer c.Close()
+	ctx := context.Background()
+
+	for range 3 {
+		whoami(t, c, "who")
+	}
+	if _, err := c.Pipelined(ctx, func(p *redis.Pipeline) error {
+		p.Get("k")
+		return nil
+	}); err != nil {
+		t.Fatalf("Pipelined: %v", err)
+	}
+	st := c.Stats()
+	if st.Dials != 1 || st.Misses != 1 || st.Hits != 3 || st.Active != 1 || st.Idle != 1 {
+		t.Fatalf("unexpected pool counters %+v", st)
+	}
+	if h := st.Commands["WHO"]; h.Count != 3 || h.Sum <= 0 || len(h.Counts) != len(h.Bounds)+1 {
+		t.Fatalf("unexpected WHO histogram %+v", h)
+	}
+	if st.Commands["PIPELINE"].Count != 1 {
+		t.Fatalf("expected one PIPELINE observation, got %+v", st.Commands)
+	}
+
+	// A read timeout discards the connection.
+	if _, err := c.Do(ctx, "HANG"); err == nil {
+		t.Fatal("expected a read timeout")
+	}
+	if st := c.Stats(); st.ErrorConns != 1 || st.Active != 0 {
+		t.Fatalf("expected the connection to be closed on error, got %+v", st)
+	}
+
+	// With the only connection pinned, a waiter gives up with its context.
+	err := c.Watch(ctx, func(*redis.Tx) error {
+		wctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		return c.Ping(wctx)
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+	if st := c.Stats(); st.Timeouts != 1 || st.WaitDuration.Count != 1 || st.WaitDuration.Sum < 20*time.Millisecond {
+		t.Fatalf("unexpected wait statistics %+v", st)
+	}
+
+	// A cancelled wait is not a timeout.
+	err = c.Watch(ctx, func(*redis.Tx) error {
+		wctx, cancel := context.WithCancel(ctx)
+		time.AfterFunc(10*time.Millisecond, cancel)
+		return c.Ping(wctx)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancellation, got %v", err)
+	}
+	if st := c.Stats(); st.Timeouts != 1 || st.WaitCancels != 1 {
+		t.Fatalf("expected 1 timeout and 1 cancel, got %d and %d", st.Timeouts, st.WaitCancels)
+	}
+}
+
+func TestClient_StatsDialAndStale(t *testing.T) {
+	errDial := errors.New("refused")
+	fail := true
+	s := nameServer(t, "a")
+	c := redis.NewClient(&redis.Options{
+		Addr:       s.addr(),
+		MaxConnAge: time.Nanosecond,
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if fail {
+				return nil, errDial
+			}
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	})
+	defer c.Close()
+
+	if err := c.Ping(context.Background()); !errors.Is(err, errDial) {
+		t.Fatalf("expected %v, got %v", errDial, err)
+	}
+	fail = false
+	whoami(t, c, "WHO")
+
+	st := c.Stats()
+	if st.Dials != 2 || st.DialErrors != 1 || st.StaleConns != 1 || st.Active != 0 {
+		t.Fatalf("unexpected counters %+v", st)
+	}
+}
+
+func TestStats_WritePrometheus(t *testing.T) {
+	st := redis.Stats{
+		PoolStats: redis.PoolStats{Active: 3, Idle: 2},
+		Hits:      7,
+		Commands: map[string]redis.Histogram{
+			"GET": {
+				Bounds: []time.Duration{time.Millisecond, time.Second},
+				Counts: []uint64{2, 1, 1},
+				Count:  4,
+				Sum:    1500 * time.Millisecond,
+			},
+		},
+	}
+	var b strings.Builder
+	if err := st.WritePrometheus(&b, "app_redis"); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	out := b.String()
+	for _, line := range []string{
+		"# TYPE app_redis_pool_connections gauge",
+		"app_redis_pool_connections 3",
+		"app_redis_pool_idle_connections 2",
+		"# TYPE app_redis_pool_hits_total counter",
+		"app_redis_pool_hits_total 7",
+		`app_redis_pool_wait_duration_seconds_bucket{le="+Inf"} 0`,
+		"app_redis_pool_wait_duration_seconds_count 0",
+		"# TYPE app_redis_command_duration_seconds histogram",
+		`app_redis_command_duration_seconds_bucket{command="GET",le="0.001"} 2`,
+		`app_redis_command_duration_seconds_bucket{command="GET",le="1"} 3`,
+		`app_redis_command_duration_seconds_bucket{command="GET",le="+Inf"} 4`,
+		`app_redis_command_duration_seconds_sum{command="GET"} 1.5`,
+		`app_redis_command_duration_seconds_count{command="GET"} 4`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+}
@@ -0,0 +1,634 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// XMessage is a stream entry.
+type XMessage struct {
+	ID     string
+	Values map[string]string
+}
+
+// XStream holds the entries read from one stream by XREAD or XREADGROUP.
+type XStream struct {
+	Stream   string
+	Messages []XMessage
+}
+
+// XAddArgs describes an XADD call.
+type XAddArgs struct {
+	Stream string
+	// ID defaults to "*" (auto-generated).
+	ID         string
+	Values     map[string]string
+	NoMkStream bool
+	// MaxLen or MinID trim the stream while adding; Approx uses "~" trimming
+	// and Limit caps the entries evicted per call.
+	MaxLen int64
+	MinID  string
+	Approx bool
+	Limit  int64
+}
+
+// XAdd appends an entry to a stream and returns its ID.
+func (c *Client) XAdd(ctx context.Context, a XAddArgs) (string, error) {
+	args := make([]any, 0, 10+2*len(a.Values))
+	args = append(args, "XADD", a.Stream)
+	if a.NoMkStream {
+		args = append(args, "NOMKSTREAM")
+	}
+	args = appendTrim(args, a.MaxLen, a.MinID, a.Approx, a.Limit)
+	if a.ID != "" {
+		args = append(args, a.ID)
+	} else {
+		args = append(args, "*")
+	}
+	for k, v := range a.Values {
+		args = append(args, k, v)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	id, ok := reply.(string)
+	if !ok {
+		return "", fmt.Errorf("redis: unexpected type %T from XADD", reply)
+	}
+	return id, nil
+}
+
+func appendTrim(args []any, maxLen int64, minID string, approx bool, limit int64) []any {
+	if maxLen <= 0 && minID == "" {
+		return args
+	}
+	if maxLen > 0 {
+		args = append(args, "MAXLEN")
+	} else {
+		args = append(args, "MINID")
+	}
+	if approx {
+		args = append(args, "~")
+	}
+	if maxLen > 0 {
+		args = append(args, maxLen)
+	} else {
+		args = append(args, minID)
+	}
+	if approx && limit > 0 {
+		args = append(args, "LIMIT", limit)
+	}
+	return args
+}
+
+// XRange returns the entries with IDs between start and stop ("-" and "+" for the ends).
+func (c *Client) XRange(ctx context.Context, stream, start, stop string) ([]XMessage, error) {
+	return c.xrange(ctx, "XRANGE", stream, start, stop, 0)
+}
+
+// XRangeN is like XRange but returns at most count entries.
+func (c *Client) XRangeN(ctx context.Context, stream, start, stop string, count int64) ([]XMessage, error) {
+	return c.xrange(ctx, "XRANGE", stream, start, stop, count)
+}
+
+// XRevRange returns the entries with IDs between start and stop in reverse order.
+// Note that start is the higher ID ("+").
+func (c *Client) XRevRange(ctx context.Context, stream, start, stop string) ([]XMessage, error) {
+	return c.xrange(ctx, "XREVRANGE", stream, start, stop, 0)
+}
+
+// XRevRangeN is like XRevRange but returns at most count entries.
+func (c *Client) XRevRangeN(ctx context.Context, stream, start, stop string, count int64) ([]XMessage, error) {
+	return c.xrange(ctx, "XREVRANGE", stream, start, stop, count)
+}
+
+func (c *Client) xrange(ctx context.Context, name, stream, start, stop string, count int64) ([]XMessage, error) {
+	args := []any{name, stream, start, stop}
+	if count > 0 {
+		args = append(args, "COUNT", count)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return xMessages(reply, name)
+}
+
+// XLen returns the number of entries in a stream.
+func (c *Client) XLen(ctx context.Context, stream string) (int64, error) {
+	return c.int64Cmd(ctx, "XLEN", stream)
+}
+
+// XTrimMaxLen trims a stream to at most maxLen entries and returns the number evicted.
+func (c *Client) XTrimMaxLen(ctx context.Context, stream string, maxLen int64, approx bool) (int64, error) {
+	return c.int64Cmd(ctx, appendTrim([]any{"XTRIM", stream}, maxLen, "", approx, 0)...)
+}
+
+// XTrimMinID evicts entries with IDs lower than minID and returns the number evicted.
+func (c *Client) XTrimMinID(ctx context.Context, stream, minID string, approx bool) (int64, error) {
+	return c.int64Cmd(ctx, appendTrim([]any{"XTRIM", stream}, 0, minID, approx, 0)...)
+}
+
+// XDel removes entries from a stream and returns the number deleted.
+func (c *Client) XDel(ctx context.Context, stream string, ids ...string) (int64, error) {
+	return c.int64Cmd(ctx, keyArgs("XDEL", ids, stream)...)
+}
+
+// XReadArgs describes an XREAD call. IDs[i] is the last ID already seen on
+// Streams[i] ("$" for only new entries).
+type XReadArgs struct {
+	Streams []string
+	IDs     []string
+	Count   int64
+	// Block waits up to this long for entries. Zero does not block; a
+	// negative value blocks indefinitely.
+	Block time.Duration
+}
+
+// XRead reads entries from one or more streams. It returns nil when a
+// blocking read times out.
+func (c *Client) XRead(ctx context.Context, a XReadArgs) ([]XStream, error) {
+	args := make([]any, 0, 6+len(a.Streams)+len(a.IDs))
+	args = append(args, "XREAD")
+	args = appendCountBlock(args, a.Count, a.Block)
+	return c.xread(ctx, "XREAD", args, a.Streams, a.IDs, a.Block)
+}
+
+// XReadGroupArgs describes an XREADGROUP call. IDs[i] is ">" for entries never
+// delivered to the group, or an ID to re-read this consumer's pending entries.
+type XReadGroupArgs struct {
+	Group    string
+	Consumer string
+	Streams  []string
+	IDs      []string
+	Count    int64
+	// Block waits up to this long for entries. Zero does not block; a
+	// negative value blocks indefinitely.
+	Block time.Duration
+	NoAck bool
+}
+
+// XReadGroup reads entries as a member of a consumer group. It returns nil
+// when a blocking read times out.
+func (c *Client) XReadGroup(ctx context.Context, a XReadGroupArgs) ([]XStream, error) {
+	args := make([]any, 0, 10+len(a.Streams)+len(a.IDs))
+	args = append(args, "XREADGROUP", "GROUP", a.Group, a.Consumer)
+	args = appendCountBlock(args, a.Count, a.Block)
+	if a.NoAck {
+		args = append(args, "NOACK")
+	}
+	return c.xread(ctx, "XREADGROUP", args, a.Streams, a.IDs, a.Block)
+}
+
+func appendCountBlock(args []any, count int64, block time.Duration) []any {
+	if count > 0 {
+		args = append(args, "COUNT", count)
+	}
+	if block > 0 {
+		args = append(args, "BLOCK", block.Milliseconds())
+	} else if block < 0 {
+		args = append(args, "BLOCK", 0)
+	}
+	return args
+}
+
+func (c *Client) xread(ctx context.Context, name string, args []any, streams, ids []string, block time.Duration) ([]XStream, error) {
+	if len(streams) != len(ids) {
+		return nil, fmt.Errorf("redis: %s needs one ID per stream", name)
+	}
+	args = append(args, "STREAMS")
+	for _, s := range streams {
+		args = append(args, s)
+	}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	if block != 0 {
+		ctx = withBlock(ctx, max(block, 0))
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	return xStreams(reply, name)
+}
+
+// XAck acknowledges entries of a consumer group and returns the number acknowledged.
+func (c *Client) XAck(ctx context.Context, stream, group string, ids ...string) (int64, error) {
+	return c.int64Cmd(ctx, keyArgs("XACK", ids, stream, group)...)
+}
+
+// XPending summarises the pending entries of a consumer group.
+type XPending struct {
+	Count     int64
+	Lower     string
+	Higher    string
+	Consumers map[string]int64
+}
+
+// XPending returns the pending entries summary of a consumer group.
+func (c *Client) XPending(ctx context.Context, stream, group string) (*XPending, error) {
+	reply, err := c.Do(ctx, "XPENDING", stream, group)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := reply.([]any)
+	if !ok || len(arr) != 4 {
+		return nil, fmt.Errorf("redis: unexpected reply %T from XPENDING", reply)
+	}
+	p := &XPending{Consumers: make(map[string]int64)}
+	p.Count, _ = arr[0].(int64)
+	p.Lower, _ = arr[1].(string)
+	p.Higher, _ = arr[2].(string)
+	consumers, _ := arr[3].([]any)
+	for _, v := range consumers {
+		pair, ok := v.([]any)
+		if !ok || len(pair) != 2 {
+			continue
+		}
+		name, _ := pair[0].(string)
+		p.Consumers[name] = toInt64(pair[1])
+	}
+	return p, nil
+}
+
+// XPendingExtArgs describes an extended XPENDING query.
+type XPendingExtArgs struct {
+	Stream   string
+	Group    string
+	Idle     time.Duration // only entries idle at least this long
+	Start    string        // defaults to "-"
+	End      string        // defaults to "+"
+	Count    int64
+	Consumer string // optional
+}
+
+// XPendingExt is a pending entry returned by the extended XPENDING form.
+type XPendingExt struct {
+	ID         string
+	Consumer   string
+	Idle       time.Duration
+	RetryCount int64
+}
+
+// XPendingExt returns details of pending entries.
+func (c *Client) XPendingExt(ctx context.Context, a XPendingExtArgs) ([]XPendingExt, error) {
+	start, end := a.Start, a.End
+	if start == "" {
+		start = "-"
+	}
+	if end == "" {
+		end = "+"
+	}
+	args := []any{"XPENDING", a.Stream, a.Group}
+	if a.Idle > 0 {
+		args = append(args, "IDLE", a.Idle.Milliseconds())
+	}
+	args = append(args, start, end, a.Count)
+	if a.Consumer != "" {
+		args = append(args, a.Consumer)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected type %T from XPENDING", reply)
+	}
+	result := make([]XPendingExt, 0, len(arr))
+	for _, v := range arr {
+		e, ok := v.([]any)
+		if !ok || len(e) != 4 {
+			return nil, fmt.Errorf("redis: unexpected entry %v from XPENDING", v)
+		}
+		var p XPendingExt
+		p.ID, _ = e[0].(string)
+		p.Consumer, _ = e[1].(string)
+		p.Idle = time.Duration(toInt64(e[2])) * time.Millisecond
+		p.RetryCount = toInt64(e[3])
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// XClaimArgs describes an XCLAIM call.
+type XClaimArgs struct {
+	Stream   string
+	Group    string
+	Consumer string
+	MinIdle  time.Duration
+	IDs      []string
+}
+
+// XClaim transfers ownership of pending entries idle for at least MinIdle to
+// Consumer and returns the claimed entries.
+func (c *Client) XClaim(ctx context.Context, a XClaimArgs) ([]XMessage, error) {
+	args := make([]any, 0, 5+len(a.IDs))
+	args = append(args, "XCLAIM", a.Stream, a.Group, a.Consumer, a.MinIdle.Milliseconds())
+	for _, id := range a.IDs {
+		args = append(args, id)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return xMessages(reply, "XCLAIM")
+}
+
+// XAutoClaimArgs describes an XAUTOCLAIM call.
+type XAutoClaimArgs struct {
+	Stream   string
+	Group    string
+	Consumer string
+	MinIdle  time.Duration
+	Start    string // defaults to "0-0"
+	Count    int64
+}
+
+// XAutoClaim claims pending entries idle for at least MinIdle, scanning from
+// Start. It returns the claimed entries and the cursor for the next call,
+// which is "0-0" once the whole pending list has been scanned.
+func (c *Client) XAutoClaim(ctx context.Context, a XAutoClaimArgs) ([]XMessage, string, error) {
+	start := a.Start
+	if start == "" {
+		start = "0-0"
+	}
+	args := []any{"XAUTOCLAIM", a.Stream, a.Group, a.Consumer, a.MinIdle.Milliseconds(), start}
+	if a.Count > 0 {
+		args = append(args, "COUNT", a.Count)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	arr, ok := reply.([]any)
+	if !ok || len(arr) < 2 {
+		return nil, "", fmt.Errorf("redis: unexpected reply %T from XAUTOCLAIM", reply)
+	}
+	next, _ := arr[0].(string)
+	msgs, err := xMessages(arr[1], "XAUTOCLAIM")
+	if err != nil {
+		return nil, "", err
+	}
+	return msgs, next, nil
+}
+
+// --- Consumer groups ---
+
+// XGroupCreate creates a consumer group starting at start ("$" for new entries only).
+func (c *Client) XGroupCreate(ctx context.Context, stream, group, start string) error {
+	_, err := c.Do(ctx, "XGROUP", "CREATE", stream, group, start)
+	return err
+}
+
+// XGroupCreateMkStream is like XGroupCreate but creates the stream if it does not exist.
+func (c *Client) XGroupCreateMkStream(ctx context.Context, stream, group, start string) error {
+	_, err := c.Do(ctx, "XGROUP", "CREATE", stream, group, start, "MKSTREAM")
+	return err
+}
+
+// XGroupSetID sets the last delivered ID of a consumer group.
+func (c *Client) XGroupSetID(ctx context.Context, stream, group, start string) error {
+	_, err := c.Do(ctx, "XGROUP", "SETID", stream, group, start)
+	return err
+}
+
+// XGroupDestroy deletes a consumer group and returns the number of groups destroyed.
+func (c *Client) XGroupDestroy(ctx context.Context, stream, group string) (int64, error) {
+	return c.int64Cmd(ctx, "XGROUP", "DESTROY", stream, group)
+}
+
+// XGroupCreateConsumer creates a consumer and returns 1 if it was created.
+func (c *Client) XGroupCreateConsumer(ctx context.Context, stream, group, consumer string) (int64, error) {
+	return c.int64Cmd(ctx, "XGROUP", "CREATECONSUMER", stream, group, consumer)
+}
+
+// XGroupDelConsumer deletes a consumer and returns the number of pending entries it had.
+func (c *Client) XGroupDelConsumer(ctx context.Context, stream, group, consumer string) (int64, error) {
+	return c.int64Cmd(ctx, "XGROUP", "DELCONSUMER", stream, group, consumer)
+}
+
+// XInfoStream is the reply of XINFO STREAM.
+type XInfoStream struct {
+	Length          int64
+	RadixTreeKeys   int64
+	RadixTreeNodes  int64
+	Groups          int64
+	LastGeneratedID string
+	FirstEntry      *XMessage
+	LastEntry       *XMessage
+}
+
+// XInfoStream returns general information about a stream.
+func (c *Client) XInfoStream(ctx context.Context, stream string) (*XInfoStream, error) {
+	reply, err := c.Do(ctx, "XINFO", "STREAM", stream)
+	if err != nil {
+		return nil, err
+	}
+	m, err := fieldMap(reply, "XINFO STREAM")
+	if err != nil {
+		return nil, err
+	}
+	info := &XInfoStream{
+		Length:         toInt64(m["length"]),
+		RadixTreeKeys:  toInt64(m["radix-tree-keys"]),
+		RadixTreeNodes: toInt64(m["radix-tree-nodes"]),
+		Groups:         toInt64(m["groups"]),
+	}
+	info.LastGeneratedID, _ = m["last-generated-id"].(string)
+	if e := m["first-entry"]; e != nil {
+		if msg, err := xMessage(e, "XINFO STREAM"); err == nil {
+			info.FirstEntry = &msg
+		}
+	}
+	if e := m["last-entry"]; e != nil {
+		if msg, err := xMessage(e, "XINFO STREAM"); err == nil {
+			info.LastEntry = &msg
+		}
+	}
+	return info, nil
+}
+
+// XInfoGroup is an entry of XINFO GROUPS.
+type XInfoGroup struct {
+	Name            string
+	Consumers       int64
+	Pending         int64
+	LastDeliveredID string
+}
+
+// XInfoGroups returns the consumer groups of a stream.
+func (c *Client) XInfoGroups(ctx context.Context, stream string) ([]XInfoGroup, error) {
+	reply, err := c.Do(ctx, "XINFO", "GROUPS", stream)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected type %T from XINFO GROUPS", reply)
+	}
+	groups := make([]XInfoGroup, 0, len(arr))
+	for _, v := range arr {
+		m, err := fieldMap(v, "XINFO GROUPS")
+		if err != nil {
+			return nil, err
+		}
+		g := XInfoGroup{
+			Consumers: toInt64(m["consumers"]),
+			Pending:   toInt64(m["pending"]),
+		}
+		g.Name, _ = m["name"].(string)
+		g.LastDeliveredID, _ = m["last-delivered-id"].(string)
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// XInfoConsumer is an entry of XINFO CONSUMERS.
+type XInfoConsumer struct {
+	Name    string
+	Pending int64
+	Idle    time.Duration
+}
+
+// XInfoConsumers returns the consumers of a consumer group.
+func (c *Client) XInfoConsumers(ctx context.Context, stream, group string) ([]XInfoConsumer, error) {
+	reply, err := c.Do(ctx, "XINFO", "CONSUMERS", stream, group)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected type %T from XINFO CONSUMERS", reply)
+	}
+	consumers := make([]XInfoConsumer, 0, len(arr))
+	for _, v := range arr {
+		m, err := fieldMap(v, "XINFO CONSUMERS")
+		if err != nil {
+			return nil, err
+		}
+		cs := XInfoConsumer{
+			Pending: toInt64(m["pending"]),
+			Idle:    time.Duration(toInt64(m["idle"])) * time.Millisecond,
+		}
+		cs.Name, _ = m["name"].(string)
+		consumers = append(consumers, cs)
+	}
+	return consumers, nil
+}
+
+// --- Stream reply helpers ---
+
+// xMessage converts an [id, [field, value, ...]] entry.
+func xMessage(v any, name string) (XMessage, error) {
+	e, ok := v.([]any)
+	if !ok || len(e) != 2 {
+		return XMessage{}, fmt.Errorf("redis: unexpected entry %v from %s", v, name)
+	}
+	id, _ := e[0].(string)
+	// Entries deleted while pending are returned with nil values by XCLAIM.
+	if e[1] == nil {
+		return XMessage{ID: id}, nil
+	}
+	values, err := stringMap(e[1], name)
+	if err != nil {
+		return XMessage{}, err
+	}
+	return XMessage{ID: id, Values: values}, nil
+}
+
+func xMessages(reply any, name string) ([]XMessage, error) {
+	arr, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected type %T from %s", reply, name)
+	}
+	msgs := make([]XMessage, 0, len(arr))
+	for _, v := range arr {
+		if v == nil {
+			continue
+		}
+		msg, err := xMessage(v, name)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// xStreams converts an XREAD reply: an array of [stream, entries] pairs in
+// RESP2, a map of stream to entries in RESP3.
+func xStreams(reply any, name string) ([]XStream, error) {
+	switch v := reply.(type) {
+	case map[any]any:
+		streams := make([]XStream, 0, len(v))
+		for k, entries := range v {
+			msgs, err := xMessages(entries, name)
+			if err != nil {
+				return nil, err
+			}
+			stream, _ := k.(string)
+			streams = append(streams, XStream{Stream: stream, Messages: msgs})
+		}
+		return streams, nil
+	case []any:
+		streams := make([]XStream, 0, len(v))
+		for _, s := range v {
+			pair, ok := s.([]any)
+			if !ok || len(pair) != 2 {
+				return nil, fmt.Errorf("redis: unexpected stream %v from %s", s, name)
+			}
+			msgs, err := xMessages(pair[1], name)
+			if err != nil {
+				return nil, err
+			}
+			stream, _ := pair[0].(string)
+			streams = append(streams, XStream{Stream: stream, Messages: msgs})
+		}
+		return streams, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected type %T from %s", reply, name)
+	}
+}
+
+// fieldMap converts a flat field/value array or RESP3 map with string keys
+// to a map keeping the raw values.
+func fieldMap(reply any, name string) (map[string]any, error) {
+	switch v := reply.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			ks, _ := k.(string)
+			m[ks] = val
+		}
+		return m, nil
+	case []any:
+		if len(v)%2 != 0 {
+			return nil, fmt.Errorf("redis: %s returned odd number of elements (%d)", name, len(v))
+		}
+		m := make(map[string]any, len(v)/2)
+		for i := 0; i < len(v); i += 2 {
+			k, _ := v[i].(string)
+			m[k] = v[i+1]
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected type %T from %s", reply, name)
+	}
+}
+
+// toInt64 converts an integer reply that may also arrive as a numeric string.
+func toInt64(v any) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case string:
+		var i int64
+		fmt.Sscan(n, &i)
+		return i
+	}
+	return 0
+}
@@ -0,0 +1,318 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+func addEntries(t *testing.T, c *redis.Client, stream string, values ...string) []string {
+	t.Helper()
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		id, err := c.XAdd(context.Background(), redis.XAddArgs{
+			Stream: stream,
+			Values: map[string]string{"v": v},
+		})
+		if err != nil {
+			t.Fatalf("XAdd: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestClient_Stream(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	ids := addEntries(t, c, "s", "a", "b", "c", "d")
+
+	n, err := c.XLen(ctx, "s")
+	if err != nil || n != 4 {
+		t.Fatalf("XLen: got %d, %v", n, err)
+	}
+
+	msgs, err := c.XRange(ctx, "s", "-", "+")
+	if err != nil {
+		t.Fatalf("XRange: %v", err)
+	}
+	if len(msgs) != 4 || msgs[0].ID != ids[0] || msgs[0].Values["v"] != "a" {
+		t.Fatalf("XRange: unexpected %v", msgs)
+	}
+
+	msgs, err = c.XRevRangeN(ctx, "s", "+", "-", 2)
+	if err != nil {
+		t.Fatalf("XRevRangeN: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Values["v"] != "d" || msgs[1].Values["v"] != "c" {
+		t.Fatalf("XRevRangeN: unexpected %v", msgs)
+	}
+
+	n, err = c.XDel(ctx, "s", ids[0])
+	if err != nil || n != 1 {
+		t.Fatalf("XDel: got %d, %v", n, err)
+	}
+
+	n, err = c.XTrimMaxLen(ctx, "s", 2, false)
+	if err != nil || n != 1 {
+		t.Fatalf("XTrimMaxLen: got %d, %v", n, err)
+	}
+	msgs, _ = c.XRange(ctx, "s", "-", "+")
+	if len(msgs) != 2 || msgs[0].ID != ids[2] {
+		t.Fatalf("after trim: unexpected %v", msgs)
+	}
+
+	n, err = c.XTrimMinID(ctx, "s", ids[3], false)
+	if err != nil || n != 1 {
+		t.Fatalf("XTrimMinID: got %d, %v", n, err)
+	}
+
+	// Capped XADD
+	for i := 0; i < 5; i++ {
+		if _, err := c.XAdd(ctx, redis.XAddArgs{Stream: "capped", MaxLen: 3, Values: map[string]string{"i": "x"}}); err != nil {
+			t.Fatalf("XAdd MAXLEN: %v", err)
+		}
+	}
+	if n, _ := c.XLen(ctx, "capped"); n != 3 {
+		t.Fatalf("capped XLen: expected 3, got %d", n)
+	}
+}
+
+func TestClient_XRead(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	ids := addEntries(t, c, "s", "a", "b")
+
+	streams, err := c.XRead(ctx, redis.XReadArgs{Streams: []string{"s"}, IDs: []string{ids[0]}})
+	if err != nil {
+		t.Fatalf("XRead: %v", err)
+	}
+	if len(streams) != 1 || streams[0].Stream != "s" || len(streams[0].Messages) != 1 {
+		t.Fatalf("XRead: unexpected %v", streams)
+	}
+	if streams[0].Messages[0].Values["v"] != "b" {
+		t.Fatalf("XRead: unexpected %v", streams[0].Messages)
+	}
+
+	// Blocking read times out with no entries.
+	start := time.Now()
+	streams, err = c.XRead(ctx, redis.XReadArgs{Streams: []string{"s"}, IDs: []string{"$"}, Block: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("XRead BLOCK: %v", err)
+	}
+	if streams != nil {
+		t.Fatalf("XRead BLOCK: expected nil, got %v", streams)
+	}
+	if time.Since(start) < 100*time.Millisecond {
+		t.Fatal("XRead BLOCK returned too early")
+	}
+
+	if _, err := c.XRead(ctx, redis.XReadArgs{Streams: []string{"s"}}); err == nil {
+		t.Fatal("expected error for missing IDs")
+	}
+}
+
+func TestClient_StreamGroup(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.XGroupCreateMkStream(ctx, "s", "g", "0"); err != nil {
+		t.Fatalf("XGroupCreateMkStream: %v", err)
+	}
+	if err := c.XGroupCreate(ctx, "s", "g", "0"); !errors.Is(err, redis.ErrBusyGroup) {
+		t.Fatalf("expected ErrBusyGroup, got %v", err)
+	}
+	ids := addEntries(t, c, "s", "a", "b", "c")
+
+	streams, err := c.XReadGroup(ctx, redis.XReadGroupArgs{
+		Group: "g", Consumer: "alice", Streams: []string{"s"}, IDs: []string{">"}, Count: 2,
+	})
+	if err != nil {
+		t.Fatalf("XReadGroup: %v", err)
+	}
+	if len(streams) != 1 || len(streams[0].Messages) != 2 {
+		t.Fatalf("XReadGroup: unexpected %v", streams)
+	}
+
+	p, err := c.XPending(ctx, "s", "g")
+	if err != nil {
+		t.Fatalf("XPending: %v", err)
+	}
+	if p.Count != 2 || p.Lower != ids[0] || p.Higher != ids[1] || p.Consumers["alice"] != 2 {
+		t.Fatalf("XPending: unexpected %+v", p)
+	}
+
+	n, err := c.XAck(ctx, "s", "g", ids[0])
+	if err != nil || n != 1 {
+		t.Fatalf("XAck: got %d, %v", n, err)
+	}
+
+	ext, err := c.XPendingExt(ctx, redis.XPendingExtArgs{Stream: "s", Group: "g", Count: 10})
+	if err != nil {
+		t.Fatalf("XPendingExt: %v", err)
+	}
+	if len(ext) != 1 || ext[0].ID != ids[1] || ext[0].Consumer != "alice" || ext[0].RetryCount != 1 {
+		t.Fatalf("XPendingExt: unexpected %+v", ext)
+	}
+
+	msgs, err := c.XClaim(ctx, redis.XClaimArgs{Stream: "s", Group: "g", Consumer: "bob", IDs: []string{ids[1]}})
+	if err != nil {
+		t.Fatalf("XClaim: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].Values["v"] != "b" {
+		t.Fatalf("XClaim: unexpected %v", msgs)
+	}
+
+	msgs, next, err := c.XAutoClaim(ctx, redis.XAutoClaimArgs{Stream: "s", Group: "g", Consumer: "carol"})
+	if err != nil {
+		t.Fatalf("XAutoClaim: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != ids[1] || next != "0-0" {
+		t.Fatalf("XAutoClaim: unexpected %v, next %q", msgs, next)
+	}
+
+	info, err := c.XInfoStream(ctx, "s")
+	if err != nil {
+		t.Fatalf("XInfoStream: %v", err)
+	}
+	if info.Length != 3 || info.Groups != 1 || info.LastGeneratedID != ids[2] {
+		t.Fatalf("XInfoStream: unexpected %+v", info)
+	}
+	if info.FirstEntry == nil || info.FirstEntry.ID != ids[0] {
+		t.Fatalf("XInfoStream: unexpected first entry %+v", info.FirstEntry)
+	}
+
+	groups, err := c.XInfoGroups(ctx, "s")
+	if err != nil {
+		t.Fatalf("XInfoGroups: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Name != "g" || groups[0].Pending != 1 {
+		t.Fatalf("XInfoGroups: unexpected %+v", groups)
+	}
+
+	consumers, err := c.XInfoConsumers(ctx, "s", "g")
+	if err != nil {
+		t.Fatalf("XInfoConsumers: %v", err)
+	}
+	pending := map[string]int64{}
+	for _, cs := range consumers {
+		pending[cs.Name] = cs.Pending
+	}
+	if pending["carol"] != 1 || pending["alice"] != 0 {
+		t.Fatalf("XInfoConsumers: unexpected %+v", consumers)
+	}
+
+	if n, err := c.XGroupDelConsumer(ctx, "s", "g", "carol"); err != nil || n != 1 {
+		t.Fatalf("XGroupDelConsumer: got %d, %v", n, err)
+	}
+	if n, err := c.XGroupDestroy(ctx, "s", "g"); err != nil || n != 1 {
+		t.Fatalf("XGroupDestroy: got %d, %v", n, err)
+	}
+	_, err = c.XReadGroup(ctx, redis.XReadGroupArgs{Group: "g", Consumer: "alice", Streams: []string{"s"}, IDs: []string{">"}})
+	if !errors.Is(err, redis.ErrNoGroup) {
+		t.Fatalf("expected ErrNoGroup, got %v", err)
+	}
+}
+
+func TestStreamConsumer(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		handled = map[string]int{}
+		failed  bool
+	)
+	sc := c.NewStreamConsumer(&redis.ConsumerOptions{
+		Stream:        "jobs",
+		Group:         "workers",
+		Consumer:      "w1",
+		Block:         50 * time.Millisecond,
+		ClaimInterval: 100 * time.Millisecond,
+		ClaimMinIdle:  time.Millisecond,
+		Handler: func(ctx context.Context, msg redis.XMessage) error {
+			mu.Lock()
+			defer mu.Unlock()
+			v := msg.Values["v"]
+			if v == "flaky" && !failed {
+				failed = true
+				return errors.New("try again later")
+			}
+			handled[v]++
+			return nil
+		},
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- sc.Run(ctx) }()
+
+	// Wait for the group to be created, then publish.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		groups, err := c.XInfoGroups(context.Background(), "jobs")
+		if err == nil && len(groups) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for consumer group")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	addEntries(t, c, "jobs", "one", "flaky", "two")
+
+	// The failed entry stays pending until the next autoclaim redelivers it.
+	deadline = time.Now().Add(3 * time.Second)
+	for {
+		p, err := c.XPending(context.Background(), "jobs", "workers")
+		if err != nil {
+			t.Fatalf("XPending: %v", err)
+		}
+		mu.Lock()
+		n := len(handled)
+		mu.Unlock()
+		if p.Count == 0 && n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out: pending %d, handled %v", p.Count, handled)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Run: expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, v := range []string{"one", "flaky", "two"} {
+		if handled[v] != 1 {
+			t.Fatalf("expected %q handled once, got %v", v, handled)
+		}
+	}
+}
+
+func TestStreamConsumer_InvalidOptions(t *testing.T) {
+	c := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer c.Close()
+	if err := c.NewStreamConsumer(&redis.ConsumerOptions{Stream: "s"}).Run(context.Background()); err == nil {
+		t.Fatal("expected error for missing options")
+	}
+}
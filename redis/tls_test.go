@@ -0,0 +1,194 @@
+package redis_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+// testPKI is a throwaway CA with a server certificate for localhost,
+// redis.test and 127.0.0.1, and a client certificate for mTLS.
+type testPKI struct {
+	pool   *x509.CertPool
+	server tls.Certificate
+	client tls.Certificate
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage, tmpl *x509.Certificate) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey: %v", err)
+		}
+		tmpl.SerialNumber = big.NewInt(serial)
+		tmpl.NotBefore = caTmpl.NotBefore
+		tmpl.NotAfter = caTmpl.NotAfter
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("CreateCertificate: %v", err)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+
+	pki := &testPKI{pool: x509.NewCertPool()}
+	pki.pool.AddCert(ca)
+	pki.server = issue(2, x509.ExtKeyUsageServerAuth, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "redis"},
+		DNSNames:    []string{"localhost", "redis.test"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+	})
+	pki.client = issue(3, x509.ExtKeyUsageClientAuth, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "client"},
+	})
+	return pki
+}
+
+// newFakeTLSServer is a fakeServer behind a TLS listener. It records the
+// SNI server name of each handshake.
+func newFakeTLSServer(t *testing.T, cfg *tls.Config, handle func(args []string) string) (*fakeServer, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var names []string
+	cfg = cfg.Clone()
+	cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		mu.Lock()
+		names = append(names, hello.ServerName)
+		mu.Unlock()
+		return nil, nil
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := startFakeServer(t, ln, handle)
+	return s, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), names...)
+	}
+}
+
+func TestClient_TLS(t *testing.T) {
+	pki := newTestPKI(t)
+	s, _ := newFakeTLSServer(t, &tls.Config{Certificates: []tls.Certificate{pki.server}}, nil)
+	ctx := context.Background()
+
+	c := redis.NewClient(&redis.Options{
+		Addr:      s.addr(),
+		TLSConfig: &tls.Config{RootCAs: pki.pool},
+	})
+	defer c.Close()
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping over TLS: %v", err)
+	}
+
+	// Without the CA the server certificate is rejected.
+	untrusted := redis.NewClient(&redis.Options{Addr: s.addr(), TLSConfig: &tls.Config{}})
+	defer untrusted.Close()
+	if err := untrusted.Ping(ctx); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected certificate error, got %v", err)
+	}
+
+	// A plain-text client cannot talk to a TLS server.
+	plain := redis.NewClient(&redis.Options{Addr: s.addr(), DialTimeout: time.Second, ReadTimeout: 200 * time.Millisecond})
+	defer plain.Close()
+	if err := plain.Ping(ctx); err == nil {
+		t.Fatal("expected plain-text PING to fail")
+	}
+}
+
+func TestClient_TLSServerName(t *testing.T) {
+	pki := newTestPKI(t)
+	s, sni := newFakeTLSServer(t, &tls.Config{Certificates: []tls.Certificate{pki.server}}, nil)
+	_, port := s.hostPort()
+	ctx := context.Background()
+
+	// SNI is taken from the dialed host...
+	c := redis.NewClient(&redis.Options{
+		Addr:      net.JoinHostPort("localhost", port),
+		TLSConfig: &tls.Config{RootCAs: pki.pool},
+	})
+	defer c.Close()
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	// ...unless ServerName is set.
+	named := redis.NewClient(&redis.Options{
+		Addr:      s.addr(),
+		TLSConfig: &tls.Config{RootCAs: pki.pool, ServerName: "redis.test"},
+	})
+	defer named.Close()
+	if err := named.Ping(ctx); err != nil {
+		t.Fatalf("Ping with ServerName: %v", err)
+	}
+
+	if got := sni(); len(got) != 2 || got[0] != "localhost" || got[1] != "redis.test" {
+		t.Fatalf("expected SNI [localhost redis.test], got %q", got)
+	}
+}
+
+func TestClient_MutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	s, _ := newFakeTLSServer(t, &tls.Config{
+		Certificates: []tls.Certificate{pki.server},
+		ClientCAs:    pki.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil)
+	ctx := context.Background()
+
+	anon := redis.NewClient(&redis.Options{
+		Addr:      s.addr(),
+		TLSConfig: &tls.Config{RootCAs: pki.pool},
+	})
+	defer anon.Close()
+	if err := anon.Ping(ctx); err == nil {
+		t.Fatal("expected PING without a client certificate to fail")
+	}
+
+	c := redis.NewClient(&redis.Options{
+		Addr: s.addr(),
+		TLSConfig: &tls.Config{
+			RootCAs:      pki.pool,
+			Certificates: []tls.Certificate{pki.client},
+		},
+	})
+	defer c.Close()
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping with client certificate: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"net"
+	"strconv"
+)
+
+// Tracer starts spans for dials, commands and pipelines when set in
+// Options.Tracer. It is small enough to be implemented on top of
+// OpenTelemetry (see the redisotel module) or any other tracing library
+// without this package depending on it.
+type Tracer interface {
+	// Start begins a span as a child of the span in ctx, if any, and
+	// returns a context carrying the new span.
+	Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span)
+}
+
+// Span is an operation started by a Tracer.
+type Span interface {
+	RecordError(err error)
+	End()
+}
+
+// Attr is a span attribute. Value is a string, int, int64 or bool.
+// Keys follow the OpenTelemetry database semantic conventions.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// Tracer returns the tracer the client was created with, or nil.
+func (c *Client) Tracer() Tracer {
+	return c.root().opts.Tracer
+}
+
+// tracingHook emits a span per dial, command and pipeline. It is installed
+// as the outermost hook by NewClient.
+type tracingHook struct {
+	tracer Tracer
+	attrs  []Attr // common to every span
+}
+
+func newTracingHook(opts *Options) *tracingHook {
+	attrs := []Attr{
+		{"db.system.name", "redis"},
+		{"db.namespace", strconv.Itoa(opts.DB)},
+	}
+	if opts.Addr != "" {
+		attrs = append(attrs, Attr{"server.address", opts.Addr})
+	}
+	return &tracingHook{tracer: opts.Tracer, attrs: attrs}
+}
+
+func (h *tracingHook) start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span) {
+	return h.tracer.Start(ctx, name, append(attrs, h.attrs...)...)
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+func (h *tracingHook) DialHook(next DialHook) DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := h.tracer.Start(ctx, "redis.dial",
+			Attr{"db.system.name", "redis"},
+			Attr{"network.transport", network},
+			Attr{"server.address", addr},
+		)
+		nc, err := next(ctx, network, addr)
+		endSpan(span, err)
+		return nc, err
+	}
+}
+
+func (h *tracingHook) ProcessHook(next ProcessHook) ProcessHook {
+	return func(ctx context.Context, args []any) (any, error) {
+		name := commandName(args)
+		ctx, span := h.start(ctx, name, Attr{"db.operation.name", name})
+		reply, err := next(ctx, args)
+		endSpan(span, err)
+		return reply, err
+	}
+}
+
+func (h *tracingHook) ProcessPipelineHook(next ProcessPipelineHook) ProcessPipelineHook {
+	return func(ctx context.Context, cmds []*Cmd) error {
+		ctx, span := h.start(ctx, "PIPELINE",
+			Attr{"db.operation.name", "PIPELINE"},
+			Attr{"db.operation.batch.size", len(cmds)},
+		)
+		err := next(ctx, cmds)
+		endSpan(span, err)
+		return err
+	}
+}
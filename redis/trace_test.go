@@ -0,0 +1,132 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+// fakeTracer records spans and their parent, taken from the context.
+type fakeTracer struct {
+	mu    sync.Mutex
+	spans []*fakeSpan
+}
+
+type fakeSpan struct {
+	t      *fakeTracer
+	name   string
+	attrs  map[string]any
+	parent *fakeSpan
+	err    error
+	ended  bool
+}
+
+type spanKey struct{}
+
+func (ft *fakeTracer) Start(ctx context.Context, name string, attrs ...redis.Attr) (context.Context, redis.Span) {
+	s := &fakeSpan{t: ft, name: name, attrs: make(map[string]any)}
+	s.parent, _ = ctx.Value(spanKey{}).(*fakeSpan)
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+	ft.mu.Lock()
+	ft.spans = append(ft.spans, s)
+	ft.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (s *fakeSpan) RecordError(err error) {
+	s.t.mu.Lock()
+	s.err = err
+	s.t.mu.Unlock()
+}
+
+func (s *fakeSpan) End() {
+	s.t.mu.Lock()
+	s.ended = true
+	s.t.mu.Unlock()
+}
+
+// take returns the recorded spans and forgets them.
+func (ft *fakeTracer) take() []*fakeSpan {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	spans := ft.spans
+	ft.spans = nil
+	return spans
+}
+
+func TestClient_Tracer(t *testing.T) {
+	s := newFakeServer(t, func(args []string) string {
+		if args[0] == "FAIL" {
+			return "-ERR boom\r\n"
+		}
+		return bulk("a")
+	})
+	ft := &fakeTracer{}
+	c := redis.NewClient(&redis.Options{Addr: s.addr(), DB: 0, Tracer: ft})
+	defer c.Close()
+	ctx := context.Background()
+
+	if c.Tracer() != ft {
+		t.Fatal("Tracer() should return Options.Tracer")
+	}
+
+	whoami(t, c, "get", "k")
+	spans := ft.take()
+	if len(spans) != 2 {
+		t.Fatalf("expected command and dial spans, got %d", len(spans))
+	}
+	cmd, dial := spans[0], spans[1]
+	if cmd.name != "GET" || cmd.attrs["db.operation.name"] != "GET" || cmd.attrs["db.system.name"] != "redis" ||
+		cmd.attrs["server.address"] != s.addr() || cmd.attrs["db.namespace"] != "0" || !cmd.ended {
+		t.Fatalf("unexpected command span %+v", cmd)
+	}
+	if dial.name != "redis.dial" || dial.parent != cmd || dial.attrs["network.transport"] != "tcp" || !dial.ended {
+		t.Fatalf("unexpected dial span %+v", dial)
+	}
+
+	if _, err := c.Do(ctx, "FAIL"); err == nil {
+		t.Fatal("expected an error reply")
+	}
+	if spans := ft.take(); len(spans) != 1 || spans[0].err == nil || !spans[0].ended {
+		t.Fatalf("expected one failed span, got %+v", spans)
+	}
+
+	if _, err := c.Pipelined(ctx, func(p *redis.Pipeline) error {
+		p.Get("x")
+		p.Get("y")
+		return nil
+	}); err != nil {
+		t.Fatalf("Pipelined: %v", err)
+	}
+	spans = ft.take()
+	if len(spans) != 1 || spans[0].name != "PIPELINE" || spans[0].attrs["db.operation.batch.size"] != 2 {
+		t.Fatalf("unexpected pipeline spans %+v", spans)
+	}
+}
+
+func TestClient_TracerDialError(t *testing.T) {
+	ft := &fakeTracer{}
+	errDial := errors.New("no route")
+	c := redis.NewClient(&redis.Options{
+		Addr:   "redis.invalid:6379",
+		Tracer: ft,
+		Dialer: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errDial
+		},
+	})
+	defer c.Close()
+
+	if err := c.Ping(context.Background()); !errors.Is(err, errDial) {
+		t.Fatalf("expected %v, got %v", errDial, err)
+	}
+	spans := ft.take()
+	if len(spans) != 2 || !errors.Is(spans[1].err, errDial) || !errors.Is(spans[0].err, errDial) {
+		t.Fatalf("expected failed PING and dial spans, got %+v", spans)
+	}
+}
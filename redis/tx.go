@@ -0,0 +1,213 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Tx is an optimistic-locking transaction bound to a single pinned connection.
+// It is only valid inside the callback passed to Client.Watch.
+type Tx struct {
+	c        *Client
+	cn       *conn
+	watching bool
+	broken   bool // an I/O error left the connection unusable
+}
+
+// TxPipeline returns a pipeline whose commands are wrapped in MULTI/EXEC.
+// Unlike Pipeline, the queued commands are applied atomically.
+func (c *Client) TxPipeline() *Pipeline {
+	return &Pipeline{c: c, multi: true}
+}
+
+// TxPipelined queues the commands issued by fn and executes them in MULTI/EXEC.
+func (c *Client) TxPipelined(ctx context.Context, fn func(p *Pipeline) error) ([]*Cmd, error) {
+	p := c.TxPipeline()
+	if err := fn(p); err != nil {
+		return nil, err
+	}
+	return p.Exec(ctx)
+}
+
+// Watch pins a connection, issues WATCH for keys and calls fn with a Tx.
+// Reads performed through tx observe the watched keys; commands queued with
+// tx.TxPipelined are applied with MULTI/EXEC and fail with ErrTxFailed if any
+// watched key was modified in the meantime. The caller decides whether to retry.
+func (c *Client) Watch(ctx context.Context, fn func(tx *Tx) error, keys ...string) error {
+	if c.cluster != nil {
+		slot := -1
+		if len(keys) > 0 {
+			slot = HashSlot(keys[0])
+		}
+		node, err := c.cluster.slotNode(ctx, slot)
+		if err != nil {
+			return err
+		}
+		c = node
+	}
+
+	cn, err := c.getConn(ctx)
+	if err != nil {
+		return err
+	}
+	tx := &Tx{c: c, cn: cn}
+	defer tx.close(ctx)
+
+	if len(keys) > 0 {
+		if _, err := tx.Do(ctx, keyArgs("WATCH", keys)...); err != nil {
+			return err
+		}
+		tx.watching = true
+	}
+	return fn(tx)
+}
+
+// close releases the pinned connection, clearing any outstanding WATCH first.
+func (tx *Tx) close(ctx context.Context) {
+	cn := tx.cn
+	tx.cn = nil
+	if cn == nil {
+		return
+	}
+	if !tx.broken && tx.watching {
+		if _, err := tx.c.execOn(ctx, cn, "UNWATCH"); err != nil {
+			tx.broken = true
+		}
+	}
+	if tx.broken {
+		tx.c.removeConn(cn)
+		return
+	}
+	tx.c.putConn(cn)
+}
+
+// Do executes a raw command immediately on the pinned connection.
+func (tx *Tx) Do(ctx context.Context, args ...any) (any, error) {
+	return tx.c.processHook(tx.process)(ctx, args)
+}
+
+func (tx *Tx) process(ctx context.Context, args []any) (any, error) {
+	if tx.cn == nil || tx.broken {
+		return nil, fmt.Errorf("redis: transaction is closed")
+	}
+	reply, err := tx.c.execOn(ctx, tx.cn, args...)
+	if err != nil {
+		var rerr RedisError
+		// A context error is harmless unless it interrupted the command.
+		if !errors.As(err, &rerr) && (err != ctx.Err() || tx.cn.written) {
+			tx.broken = true
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+// cmd runs a command immediately and wraps the reply for typed access.
+func (tx *Tx) cmd(ctx context.Context, args ...any) *Cmd {
+	cmd := &Cmd{args: args}
+	cmd.val, cmd.err = tx.Do(ctx, args...)
+	return cmd
+}
+
+// Get returns the value of key, or empty string if key does not exist.
+func (tx *Tx) Get(ctx context.Context, key string) (string, error) {
+	return tx.cmd(ctx, "GET", key).Text()
+}
+
+// HGet returns the value of field in hash key.
+func (tx *Tx) HGet(ctx context.Context, key, field string) (string, error) {
+	return tx.cmd(ctx, "HGET", key, field).Text()
+}
+
+// HGetAll returns all field-value pairs in hash key.
+func (tx *Tx) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return tx.cmd(ctx, "HGETALL", key).StringMap()
+}
+
+// Exists returns the number of specified keys that exist.
+func (tx *Tx) Exists(ctx context.Context, keys ...string) (int64, error) {
+	return tx.cmd(ctx, keyArgs("EXISTS", keys)...).Int64()
+}
+
+// Unwatch flushes all watched keys.
+func (tx *Tx) Unwatch(ctx context.Context) error {
+	if _, err := tx.Do(ctx, "UNWATCH"); err != nil {
+		return err
+	}
+	tx.watching = false
+	return nil
+}
+
+// TxPipeline returns a MULTI/EXEC pipeline bound to the pinned connection.
+func (tx *Tx) TxPipeline() *Pipeline {
+	return &Pipeline{c: tx.c, tx: tx, multi: true}
+}
+
+// TxPipelined queues the commands issued by fn and executes them in MULTI/EXEC
+// on the pinned connection. It returns ErrTxFailed if a watched key changed.
+func (tx *Tx) TxPipelined(ctx context.Context, fn func(p *Pipeline) error) ([]*Cmd, error) {
+	p := tx.TxPipeline()
+	if err := fn(p); err != nil {
+		return nil, err
+	}
+	return p.Exec(ctx)
+}
+
+// execPipeline runs cmds in MULTI/EXEC on the pinned connection.
+func (tx *Tx) execPipeline(ctx context.Context, cmds []*Cmd) error {
+	if tx.cn == nil || tx.broken {
+		err := fmt.Errorf("redis: transaction is closed")
+		setCmdsErr(cmds, err)
+		return err
+	}
+	if err := tx.c.txPipelineOn(ctx, tx.cn, cmds); err != nil {
+		tx.broken = true
+		return err
+	}
+	// EXEC always clears watched keys, whatever its outcome.
+	tx.watching = false
+	return nil
+}
+
+// txPipelineOn wraps cmds in MULTI/EXEC and distributes the EXEC reply.
+// A non-nil return value is an I/O error that leaves cn unusable.
+func (c *Client) txPipelineOn(ctx context.Context, cn *conn, cmds []*Cmd) error {
+	exec := &Cmd{args: []any{"EXEC"}}
+	all := make([]*Cmd, 0, len(cmds)+2)
+	all = append(all, &Cmd{args: []any{"MULTI"}})
+	all = append(all, cmds...)
+	all = append(all, exec)
+
+	if err := c.pipelineOn(ctx, cn, all); err != nil {
+		return err
+	}
+
+	// Each queued command replied QUEUED (or an error if it was rejected).
+	for _, cmd := range cmds {
+		cmd.val = nil
+	}
+
+	if exec.err != nil {
+		// EXECABORT: keep the original error of rejected commands.
+		setCmdsErr(cmds, exec.err)
+		return nil
+	}
+	if exec.val == nil {
+		setCmdsErr(cmds, ErrTxFailed)
+		return nil
+	}
+	arr, ok := exec.val.([]any)
+	if !ok || len(arr) != len(cmds) {
+		setCmdsErr(cmds, fmt.Errorf("redis: unexpected EXEC reply %T", exec.val))
+		return nil
+	}
+	for i, cmd := range cmds {
+		if e, ok := arr[i].(RedisError); ok {
+			cmd.err = e
+			continue
+		}
+		cmd.val = arr[i]
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+func TestTxPipeline(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	cmds, err := c.TxPipelined(ctx, func(p *redis.Pipeline) error {
+		p.Set("txk", "v", 0)
+		p.Incr("txcounter")
+		p.Get("txk")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TxPipelined: %v", err)
+	}
+	if len(cmds) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(cmds))
+	}
+	if n, _ := cmds[1].Int64(); n != 1 {
+		t.Fatalf("INCR: expected 1, got %d", n)
+	}
+	if s, _ := cmds[2].Text(); s != "v" {
+		t.Fatalf("GET: expected v, got %q", s)
+	}
+}
+
+func TestTxPipeline_ExecAbort(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	p := c.TxPipeline()
+	set := p.Set("txabort", "v", 0)
+	bad := p.Do("SET", "txabort") // wrong number of arguments
+	_, err := p.Exec(ctx)
+	if err == nil {
+		t.Fatal("expected error from aborted transaction")
+	}
+	if bad.Err() == nil {
+		t.Fatal("expected error on rejected command")
+	}
+	if !errors.Is(set.Err(), redis.ErrExecAbort) {
+		t.Fatalf("expected ErrExecAbort on queued command, got %v", set.Err())
+	}
+
+	val, _ := c.Get(ctx, "txabort")
+	if val != "" {
+		t.Fatalf("aborted transaction must not apply, got %q", val)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	c.Set(ctx, "balance", "10", 0)
+
+	err := c.Watch(ctx, func(tx *redis.Tx) error {
+		s, err := tx.Get(ctx, "balance")
+		if err != nil {
+			return err
+		}
+		n, _ := strconv.Atoi(s)
+		_, err = tx.TxPipelined(ctx, func(p *redis.Pipeline) error {
+			p.Set("balance", strconv.Itoa(n+5), 0)
+			return nil
+		})
+		return err
+	}, "balance")
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	val, _ := c.Get(ctx, "balance")
+	if val != "15" {
+		t.Fatalf("expected 15, got %q", val)
+	}
+}
+
+func TestWatch_Conflict(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	c.Set(ctx, "wk", "1", 0)
+
+	err := c.Watch(ctx, func(tx *redis.Tx) error {
+		if _, err := tx.Get(ctx, "wk"); err != nil {
+			return err
+		}
+		// Modify the watched key from another connection.
+		if err := c.Set(ctx, "wk", "changed", 0); err != nil {
+			return err
+		}
+		_, err := tx.TxPipelined(ctx, func(p *redis.Pipeline) error {
+			p.Set("wk", "mine", 0)
+			return nil
+		})
+		return err
+	}, "wk")
+	if !errors.Is(err, redis.ErrTxFailed) {
+		t.Fatalf("expected ErrTxFailed, got %v", err)
+	}
+
+	val, _ := c.Get(ctx, "wk")
+	if val != "changed" {
+		t.Fatalf("expected changed, got %q", val)
+	}
+}
+
+func TestWatch_CallbackError(t *testing.T) {
+	c := newTestClient(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	errAbort := errors.New("abort")
+	var saved *redis.Tx
+	err := c.Watch(ctx, func(tx *redis.Tx) error {
+		saved = tx
+		return errAbort
+	}, "wk2")
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected callback error, got %v", err)
+	}
+
+	if _, err := saved.Get(ctx, "wk2"); err == nil {
+		t.Fatal("expected error using Tx after Watch returned")
+	}
+
+	// The connection is back in the pool and no longer watching.
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	stats := c.PoolStats()
+	if stats.Active != stats.Idle {
+		t.Fatalf("expected all connections idle, got %+v", stats)
+	}
+}
@@ -0,0 +1,23 @@
+module github.com/yshengliao/goscriptor/redisotel
+
+go 1.25
+
+require (
+	github.com/yshengliao/goscriptor v0.0.0-20261018074252-7b5cfd9a02d2
+	go.opentelemetry.io/otel v1.38.0
+	go.opentelemetry.io/otel/sdk v1.38.0
+	go.opentelemetry.io/otel/trace v1.38.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.38.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
+
+// Builds against the module in the parent directory when working in this
+// repository. Go ignores it when redisotel is required by another module.
+replace github.com/yshengliao/goscriptor => ../
@@ -0,0 +1,80 @@
+// Package redisotel adapts OpenTelemetry tracing to redis.Tracer.
+//
+// It lives in its own module so that goscriptor and its redis package stay
+// free of dependencies:
+//
+//	client := redis.NewClient(&redis.Options{
+//	    Addr:   "127.0.0.1:6379",
+//	    Tracer: redisotel.NewTracer(otel.GetTracerProvider()),
+//	})
+//
+// Spans are emitted for every dial, command and pipeline, and by goscriptor
+// for script registration, ExecSha and NOSCRIPT reloads.
+package redisotel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+const instrumentationName = "github.com/yshengliao/goscriptor/redisotel"
+
+// NewTracer returns a redis.Tracer that starts client spans from tp.
+// A nil tp uses the global TracerProvider.
+func NewTracer(tp trace.TracerProvider) redis.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &tracer{t: tp.Tracer(instrumentationName)}
+}
+
+type tracer struct {
+	t trace.Tracer
+}
+
+func (t *tracer) Start(ctx context.Context, name string, attrs ...redis.Attr) (context.Context, redis.Span) {
+	kvs := make([]attribute.KeyValue, len(attrs))
+	for i, a := range attrs {
+		kvs[i] = keyValue(a)
+	}
+	ctx, span := t.t.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(kvs...),
+	)
+	return ctx, otelSpan{span}
+}
+
+type otelSpan struct {
+	trace.Span
+}
+
+func (s otelSpan) RecordError(err error) {
+	s.Span.RecordError(err)
+	s.Span.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() { s.Span.End() }
+
+func keyValue(a redis.Attr) attribute.KeyValue {
+	switch v := a.Value.(type) {
+	case string:
+		return attribute.String(a.Key, v)
+	case int:
+		return attribute.Int(a.Key, v)
+	case int64:
+		return attribute.Int64(a.Key, v)
+	case bool:
+		return attribute.Bool(a.Key, v)
+	case float64:
+		return attribute.Float64(a.Key, v)
+	default:
+		return attribute.String(a.Key, fmt.Sprint(v))
+	}
+}
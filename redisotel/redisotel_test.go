@@ -0,0 +1,63 @@
+package redisotel_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/yshengliao/goscriptor/redis"
+	"github.com/yshengliao/goscriptor/redisotel"
+)
+
+func TestNewTracer(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	errDial := errors.New("no route")
+	c := redis.NewClient(&redis.Options{
+		Addr:   "redis.invalid:6379",
+		Tracer: redisotel.NewTracer(tp),
+		Dialer: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errDial
+		},
+	})
+	defer c.Close()
+
+	if err := c.Ping(context.Background()); !errors.Is(err, errDial) {
+		t.Fatalf("expected %v, got %v", errDial, err)
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	dial, ping := spans[0], spans[1]
+	if ping.Name() != "PING" || ping.SpanKind() != trace.SpanKindClient || ping.Status().Code != codes.Error {
+		t.Fatalf("unexpected PING span: %s %v %v", ping.Name(), ping.SpanKind(), ping.Status())
+	}
+	if dial.Name() != "redis.dial" || dial.Parent().SpanID() != ping.SpanContext().SpanID() {
+		t.Fatalf("expected redis.dial child of PING, got %s", dial.Name())
+	}
+
+	want := map[attribute.Key]attribute.Value{
+		"db.system.name":    attribute.StringValue("redis"),
+		"db.operation.name": attribute.StringValue("PING"),
+		"server.address":    attribute.StringValue("redis.invalid:6379"),
+	}
+	got := make(map[attribute.Key]attribute.Value)
+	for _, kv := range ping.Attributes() {
+		got[kv.Key] = kv.Value
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("attribute %s: expected %v, got %v", k, v.Emit(), got[k].Emit())
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package goscriptor
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+// definitionIndex is the hash, in the script DB, that maps every registered
+// script definition to the Unix time of its last use.
+const definitionIndex = "scriptor_definitions"
+
+// delDefinitionLuaScriptTemplate deletes the definition ARGV[2] and its entry
+// in the index KEYS[1], if it is indexed and, unless ARGV[3] is empty, was
+// last used before the Unix time ARGV[3]. KEYS[2] is the definition; it is
+// left out on a cluster, where it may be on another node than the index.
+var delDefinitionLuaScriptTemplate = `
+	redis.pcall('SELECT', ARGV[1])
+	local used = redis.call('HGET', KEYS[1], ARGV[2])
+	if not used or (ARGV[3] ~= '' and tonumber(used) >= tonumber(ARGV[3])) then
+		return 0
+	end
+	redis.call('HDEL', KEYS[1], ARGV[2])
+	if KEYS[2] then
+		redis.call('DEL', KEYS[2])
+	end
+	return 1
+`
+
+// Definition is a registered script definition, e.g. "myapp|v1.0".
+type Definition struct {
+	Name     string
+	Scripts  map[string]string // script name -> SHA1
+	LastUsed time.Time         // last Register or LoadScripts of the definition
+}
+
+// ScriptDiff lists the script names that differ between two definitions.
+type ScriptDiff struct {
+	Added   []string // only in the newer definition
+	Removed []string // only in the older definition
+	Changed []string // in both, with a different SHA1
+}
+
+// Scripts returns a copy of the script name to SHA1 map.
+func (sd *ScriptDescriptor) Scripts() map[string]string {
+	scripts := make(map[string]string, len(sd.container))
+	for name, sha := range sd.container {
+		scripts[name] = sha
+	}
+	return scripts
+}
+
+// Definitions lists the script definitions registered in db, sorted by name.
+// Definitions registered before the index was introduced are listed after
+// their next Register, or once adopted with AdoptDefinitions.
+func (sd *ScriptDescriptor) Definitions(ctx context.Context, client *redis.Client, db int) ([]Definition, error) {
+	if client == nil {
+		return nil, ErrNilClient
+	}
+	return listDefinitions(ctx, client, db)
+}
+
+// Diff compares the scripts of definitions from and to. It returns
+// ErrKeyNotFound if either does not exist.
+func (sd *ScriptDescriptor) Diff(ctx context.Context, client *redis.Client, from, to string, db int) (ScriptDiff, error) {
+	if client == nil {
+		return ScriptDiff{}, ErrNilClient
+	}
+	return diffDefinitions(ctx, client, from, to, db)
+}
+
+// DeleteDefinition removes a definition from db. The scripts stay in the
+// Redis script cache, where other definitions may still use them. It returns
+// ErrKeyNotFound if the definition is not in the index.
+func (sd *ScriptDescriptor) DeleteDefinition(ctx context.Context, client *redis.Client, redisScriptDefinition string, db int) error {
+	if client == nil {
+		return ErrNilClient
+	}
+	return deleteDefinition(ctx, client, redisScriptDefinition, db)
+}
+
+// Prune deletes the definitions in db that were not registered or loaded for
+// longer than unused, and returns their names. Keys missing from the index
+// are never deleted.
+func (sd *ScriptDescriptor) Prune(ctx context.Context, client *redis.Client, unused time.Duration, db int) ([]string, error) {
+	if client == nil {
+		return nil, ErrNilClient
+	}
+	return pruneDefinitions(ctx, client, unused, db, "")
+}
+
+// AdoptDefinitions adds definitions registered before the index was
+// introduced to the index, as used now, so that Definitions lists them and
+// Prune may delete them later. It returns ErrKeyNotFound if one of them does
+// not exist; indexed definitions are left as they are.
+func (sd *ScriptDescriptor) AdoptDefinitions(ctx context.Context, client *redis.Client, db int, names ...string) error {
+	if client == nil {
+		return ErrNilClient
+	}
+	return adoptDefinitions(ctx, client, db, names)
+}
+
+// touchDefinition records now as the last use of a definition.
+func touchDefinition(ctx context.Context, client *redis.Client, redisScriptDefinition string, db int) error {
+	_, err := registryCall(ctx, client, setLuaScriptTemplate, db, "HSET", definitionIndex, redisScriptDefinition, time.Now().Unix())
+	return err
+}
+
+func listDefinitions(ctx context.Context, client *redis.Client, db int) ([]Definition, error) {
+	used, err := definitionTimes(ctx, client, db)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := make([]Definition, 0, len(used))
+	for name, lastUsed := range used {
+		scripts, err := getLuaScripts(ctx, client, name, db)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, Definition{Name: name, Scripts: scripts, LastUsed: lastUsed})
+	}
+	slices.SortFunc(defs, func(a, b Definition) int { return cmp.Compare(a.Name, b.Name) })
+	return defs, nil
+}
+
+func diffDefinitions(ctx context.Context, client *redis.Client, from, to string, db int) (ScriptDiff, error) {
+	old, err := getLuaScripts(ctx, client, from, db)
+	if err != nil {
+		return ScriptDiff{}, err
+	}
+	cur, err := getLuaScripts(ctx, client, to, db)
+	if err != nil {
+		return ScriptDiff{}, err
+	}
+	if len(old) == 0 || len(cur) == 0 {
+		return ScriptDiff{}, ErrKeyNotFound
+	}
+
+	var diff ScriptDiff
+	for name, sha := range cur {
+		oldSha, ok := old[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, name)
+		case oldSha != sha:
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range old {
+		if _, ok := cur[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	slices.Sort(diff.Added)
+	slices.Sort(diff.Removed)
+	slices.Sort(diff.Changed)
+	return diff, nil
+}
+
+// definitionTimes returns the last use of every indexed definition.
+func definitionTimes(ctx context.Context, client *redis.Client, db int) (map[string]time.Time, error) {
+	index, err := getLuaScripts(ctx, client, definitionIndex, db)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]time.Time, len(index))
+	for name, v := range index {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("goscriptor: invalid last-used time %q for %q", v, name)
+		}
+		used[name] = time.Unix(sec, 0)
+	}
+
+	return used, nil
+}
+
+func adoptDefinitions(ctx context.Context, client *redis.Client, db int, names []string) error {
+	for _, name := range names {
+		if err := keyExistsLuaScript(ctx, client, name, db); err != nil {
+			return fmt.Errorf("goscriptor: adopt %q: %w", name, err)
+		}
+		err := mkeyExistsLuaScript(ctx, client, definitionIndex, name, db)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrKeyNotFound) {
+			return err
+		}
+		if err := touchDefinition(ctx, client, name, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteDefinition deletes an indexed definition. A key missing from the
+// index is left alone: it may not be a definition.
+func deleteDefinition(ctx context.Context, client *redis.Client, redisScriptDefinition string, db int) error {
+	deleted, err := removeDefinition(ctx, client, redisScriptDefinition, time.Time{}, db)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// removeDefinition deletes an indexed definition last used before cutoff, or
+// at any time if cutoff is zero. The check and the delete run in one script,
+// so a Register in between keeps its definition. On a cluster only the index
+// entry is removed by the script; the hash is deleted next.
+func removeDefinition(ctx context.Context, client *redis.Client, redisScriptDefinition string, cutoff time.Time, db int) (bool, error) {
+	var before string
+	if !cutoff.IsZero() {
+		before = strconv.FormatInt(cutoff.Unix(), 10)
+	}
+	keys := []string{definitionIndex, redisScriptDefinition}
+	if client.IsCluster() {
+		keys = keys[:1]
+	}
+	res, err := client.Eval(ctx, delDefinitionLuaScriptTemplate, keys, db, redisScriptDefinition, before)
+	if err != nil || res != int64(1) {
+		return false, err
+	}
+	if client.IsCluster() {
+		if _, err := client.Do(ctx, "DEL", redisScriptDefinition); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// pruneDefinitions deletes the definitions unused for longer than unused,
+// except keep.
+func pruneDefinitions(ctx context.Context, client *redis.Client, unused time.Duration, db int, keep string) ([]string, error) {
+	used, err := definitionTimes(ctx, client, db)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-unused)
+	var pruned []string
+	for name, lastUsed := range used {
+		if name == keep || !lastUsed.Before(cutoff) {
+			continue
+		}
+		// The index may have changed since it was read: check again.
+		deleted, err := removeDefinition(ctx, client, name, cutoff, db)
+		if err != nil {
+			return pruned, err
+		}
+		if deleted {
+			pruned = append(pruned, name)
+		}
+	}
+	slices.Sort(pruned)
+	return pruned, nil
+}
@@ -0,0 +1,347 @@
+package goscriptor
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+func TestScriptDescriptor_Registry(t *testing.T) {
+	client := testRedisClient(t)
+	defer client.Close()
+	ctx := context.Background()
+
+	const v1, v2 = "app|v1", "app|v2"
+	sd := &ScriptDescriptor{}
+	if err := sd.Register(ctx, client, map[string]string{hello: helloScript, "bye": `return 'bye'`}, v1, 1); err != nil {
+		t.Fatalf("Register v1: %v", err)
+	}
+	if err := sd.Register(ctx, client, map[string]string{hello: helloScript, "bye": `return 'Bye'`, "new": `return 1`}, v2, 1); err != nil {
+		t.Fatalf("Register v2: %v", err)
+	}
+	if scripts := sd.Scripts(); len(scripts) != 3 || scripts["new"] == "" {
+		t.Fatalf("expected the 3 scripts of v2, got %v", scripts)
+	}
+
+	defs, err := sd.Definitions(ctx, client, 1)
+	if err != nil {
+		t.Fatalf("Definitions: %v", err)
+	}
+	if len(defs) != 2 || defs[0].Name != v1 || defs[1].Name != v2 || len(defs[0].Scripts) != 2 {
+		t.Fatalf("unexpected definitions %+v", defs)
+	}
+	if d := time.Since(defs[1].LastUsed); d < 0 || d > time.Minute {
+		t.Fatalf("expected LastUsed about now, got %v", defs[1].LastUsed)
+	}
+
+	diff, err := sd.Diff(ctx, client, v1, v2, 1)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if !slices.Equal(diff.Added, []string{"new"}) || len(diff.Removed) != 0 || !slices.Equal(diff.Changed, []string{"bye"}) {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+	if _, err := sd.Diff(ctx, client, v1, "app|v3", 1); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+
+	// v1 was last registered 40 days ago.
+	old := strconv.FormatInt(time.Now().Add(-40*24*time.Hour).Unix(), 10)
+	if err := setLuaScript(ctx, client, definitionIndex, v1, old, 1); err != nil {
+		t.Fatalf("set last use: %v", err)
+	}
+	pruned, err := sd.Prune(ctx, client, 30*24*time.Hour, 1)
+	if err != nil || !slices.Equal(pruned, []string{v1}) {
+		t.Fatalf("expected %s to be pruned, got %v, %v", v1, pruned, err)
+	}
+	if defs, _ := sd.Definitions(ctx, client, 1); len(defs) != 1 || defs[0].Name != v2 {
+		t.Fatalf("expected only %s left, got %+v", v2, defs)
+	}
+
+	if err := sd.DeleteDefinition(ctx, client, v2, 1); err != nil {
+		t.Fatalf("DeleteDefinition: %v", err)
+	}
+	if err := sd.DeleteDefinition(ctx, client, v2, 1); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+	if defs, _ := sd.Definitions(ctx, client, 1); len(defs) != 0 {
+		t.Fatalf("expected no definitions, got %+v", defs)
+	}
+}
+
+func TestScriptDescriptor_AdoptDefinitions(t *testing.T) {
+	client := testRedisClient(t)
+	defer client.Close()
+	ctx := context.Background()
+
+	// Registered by a release without the index.
+	sd := &ScriptDescriptor{}
+	if err := sd.Register(ctx, client, map[string]string{hello: helloScript}, "app|v0", 1); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	hdel := `redis.pcall('SELECT', ARGV[1]) return redis.call('HDEL', KEYS[1], ARGV[2])`
+	if _, err := registryCall(ctx, client, hdel, 1, "HDEL", definitionIndex, "app|v0"); err != nil {
+		t.Fatalf("drop index entry: %v", err)
+	}
+	// Application data that looks like a definition.
+	token := sd.container[hello]
+	if err := setLuaScript(ctx, client, "tokens", "user", token, 1); err != nil {
+		t.Fatalf("set hash: %v", err)
+	}
+
+	if defs, err := sd.Definitions(ctx, client, 1); err != nil || len(defs) != 0 {
+		t.Fatalf("expected no indexed definition, got %+v, %v", defs, err)
+	}
+	if err := sd.DeleteDefinition(ctx, client, "tokens", 1); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+	if pruned, err := sd.Prune(ctx, client, 0, 1); err != nil || len(pruned) != 0 {
+		t.Fatalf("expected nothing to prune, got %v, %v", pruned, err)
+	}
+	if err := keyExistsLuaScript(ctx, client, "tokens", 1); err != nil {
+		t.Fatalf("expected tokens to be kept: %v", err)
+	}
+
+	if err := sd.AdoptDefinitions(ctx, client, 1, "app|v0", "app|v9"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound for app|v9, got %v", err)
+	}
+	defs, err := sd.Definitions(ctx, client, 1)
+	if err != nil {
+		t.Fatalf("Definitions: %v", err)
+	}
+	if len(defs) != 1 || defs[0].Name != "app|v0" || defs[0].Scripts[hello] != token {
+		t.Fatalf("expected the adopted definition, got %+v", defs)
+	}
+	if d := time.Since(defs[0].LastUsed); d < 0 || d > time.Minute {
+		t.Fatalf("expected LastUsed about now, got %v", defs[0].LastUsed)
+	}
+}
+
+func TestScriptDescriptor_LoadScriptsTouch(t *testing.T) {
+	client := testRedisClient(t)
+	defer client.Close()
+	ctx := context.Background()
+
+	sd := &ScriptDescriptor{}
+	if err := sd.Register(ctx, client, map[string]string{hello: helloScript}, "app|v1", 1); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	old := strconv.FormatInt(time.Now().Add(-40*24*time.Hour).Unix(), 10)
+	if err := setLuaScript(ctx, client, definitionIndex, "app|v1", old, 1); err != nil {
+		t.Fatalf("set last use: %v", err)
+	}
+
+	// A process that only loads the definition keeps it from being pruned.
+	if err := (&ScriptDescriptor{}).LoadScripts(ctx, client, "app|v1", 1); err != nil {
+		t.Fatalf("LoadScripts: %v", err)
+	}
+	if pruned, err := sd.Prune(ctx, client, 30*24*time.Hour, 1); err != nil || len(pruned) != 0 {
+		t.Fatalf("expected nothing to prune, got %v, %v", pruned, err)
+	}
+}
+
+// fakeRegistry is a server that runs the registry scripts on in-memory
+// hashes, for tests without Redis.
+type fakeRegistry struct {
+	ln net.Listener
+
+	mu          sync.Mutex
+	hashes      map[string]map[string]string
+	readOnly    bool                               // writes fail with READONLY
+	onIndexRead func(map[string]map[string]string) // called after a read of the index
+}
+
+func newFakeRegistry(t *testing.T, hashes map[string]map[string]string) (*fakeRegistry, *redis.Client) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeRegistry{ln: ln, hashes: hashes}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	client := redis.NewClient(&redis.Options{Addr: ln.Addr().String(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return f, client
+}
+
+func (f *fakeRegistry) serve() {
+	for {
+		nc, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer nc.Close()
+			rd := bufio.NewReader(nc)
+			for {
+				reply, err := redis.ReadReply(rd)
+				if err != nil {
+					return
+				}
+				arr, _ := reply.([]any)
+				args := make([]string, len(arr))
+				for i, v := range arr {
+					args[i], _ = v.(string)
+				}
+				if _, err := nc.Write([]byte(f.handle(args))); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (f *fakeRegistry) hash(key string) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.hashes[key]
+}
+
+func bulk(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+
+func (f *fakeRegistry) handle(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch strings.ToUpper(args[0]) + " " + strings.ToUpper(args[1]) {
+	case "SCRIPT LOAD":
+		sum := sha1.Sum([]byte(args[2]))
+		return bulk(hex.EncodeToString(sum[:]))
+	case "SCRIPT EXISTS":
+		return "*1\r\n:1\r\n"
+	}
+	if strings.ToUpper(args[0]) != "EVAL" {
+		return "-ERR unknown command\r\n"
+	}
+	n, _ := strconv.Atoi(args[2])
+	keys, argv := args[3:3+n], args[3+n:]
+	h := f.hashes[keys[0]]
+	switch args[1] {
+	case loadLuaScriptTemplate:
+		out := fmt.Sprintf("*%d\r\n", 2*len(h))
+		for k, v := range h {
+			out += bulk(k) + bulk(v)
+		}
+		if keys[0] == definitionIndex && f.onIndexRead != nil {
+			f.onIndexRead(f.hashes)
+		}
+		return out
+	case setLuaScriptTemplate:
+		if f.readOnly {
+			return "-READONLY You can't write against a read only replica.\r\n"
+		}
+		if h == nil {
+			h = make(map[string]string)
+			f.hashes[keys[0]] = h
+		}
+		h[argv[1]] = argv[2]
+		return ":1\r\n"
+	case existsLuaScriptTemplate:
+		if h == nil {
+			return ":0\r\n"
+		}
+		return ":1\r\n"
+	case hexistsLuaScriptTemplate:
+		if _, ok := h[argv[1]]; !ok {
+			return ":0\r\n"
+		}
+		return ":1\r\n"
+	case delDefinitionLuaScriptTemplate:
+		used, ok := h[argv[1]]
+		if !ok {
+			return ":0\r\n"
+		}
+		if argv[2] != "" {
+			u, _ := strconv.ParseInt(used, 10, 64)
+			before, _ := strconv.ParseInt(argv[2], 10, 64)
+			if u >= before {
+				return ":0\r\n"
+			}
+		}
+		delete(h, argv[1])
+		if len(keys) > 1 {
+			delete(f.hashes, keys[1])
+		}
+		return ":1\r\n"
+	}
+	return "-ERR unknown script\r\n"
+}
+
+func TestScriptDescriptor_IndexOnly(t *testing.T) {
+	sha := strings.Repeat("a", 40)
+	f, client := newFakeRegistry(t, map[string]map[string]string{
+		definitionIndex: {"app|v1": strconv.FormatInt(time.Now().Unix(), 10)},
+		"app|v1":        {hello: sha},
+		"tokens":        {"user": sha}, // not a definition, though it looks like one
+	})
+	ctx := context.Background()
+	sd := &ScriptDescriptor{}
+
+	defs, err := sd.Definitions(ctx, client, 1)
+	if err != nil {
+		t.Fatalf("Definitions: %v", err)
+	}
+	if len(defs) != 1 || defs[0].Name != "app|v1" {
+		t.Fatalf("expected only the indexed definition, got %+v", defs)
+	}
+	if err := sd.DeleteDefinition(ctx, client, "tokens", 1); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+	if pruned, err := sd.Prune(ctx, client, -time.Hour, 1); err != nil || !slices.Equal(pruned, []string{"app|v1"}) {
+		t.Fatalf("expected app|v1 to be pruned, got %v, %v", pruned, err)
+	}
+	if f.hash("tokens") == nil || f.hash("app|v1") != nil {
+		t.Fatalf("expected tokens to be kept and app|v1 deleted")
+	}
+}
+
+func TestScriptDescriptor_PruneRegisteredAgain(t *testing.T) {
+	sha := strings.Repeat("a", 40)
+	old := strconv.FormatInt(time.Now().Add(-40*24*time.Hour).Unix(), 10)
+	f, client := newFakeRegistry(t, map[string]map[string]string{
+		definitionIndex: {"app|v1": old, "app|v2": old},
+		"app|v1":        {hello: sha},
+		"app|v2":        {hello: sha},
+	})
+	// Another instance registers app|v1 again once Prune has read the index.
+	f.onIndexRead = func(hashes map[string]map[string]string) {
+		hashes[definitionIndex]["app|v1"] = strconv.FormatInt(time.Now().Unix(), 10)
+		f.onIndexRead = nil
+	}
+
+	pruned, err := (&ScriptDescriptor{}).Prune(context.Background(), client, 30*24*time.Hour, 1)
+	if err != nil || !slices.Equal(pruned, []string{"app|v2"}) {
+		t.Fatalf("expected only app|v2 to be pruned, got %v, %v", pruned, err)
+	}
+	if f.hash("app|v1") == nil || f.hash(definitionIndex)["app|v1"] == "" {
+		t.Fatal("expected app|v1 to be kept")
+	}
+}
+
+func TestScriptDescriptor_LoadScriptsReadOnly(t *testing.T) {
+	sha := strings.Repeat("a", 40)
+	f, client := newFakeRegistry(t, map[string]map[string]string{
+		"app|v1": {hello: sha},
+	})
+	f.readOnly = true
+
+	sd := &ScriptDescriptor{}
+	if err := sd.LoadScripts(context.Background(), client, "app|v1", 1); err != nil {
+		t.Fatalf("LoadScripts: %v", err)
+	}
+	if sd.container[hello] != sha {
+		t.Fatalf("expected %s, got %v", sha, sd.container)
+	}
+}
@@ -2,6 +2,7 @@ package goscriptor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/yshengliao/goscriptor/redis"
@@ -49,6 +50,16 @@ var (
 	`
 )
 
+// registryCall runs a command on the registry hash. On a standalone client it
+// runs through a Lua template that SELECTs the script DB first; Redis Cluster
+// has no SELECT, so there the command is sent directly and routed by key.
+func registryCall(ctx context.Context, client *redis.Client, template string, db int, cmd string, key string, args ...any) (any, error) {
+	if client.IsCluster() {
+		return client.Do(ctx, append([]any{cmd, key}, args...)...)
+	}
+	return client.Eval(ctx, template, []string{key}, append([]any{db}, args...)...)
+}
+
 // ScriptDescriptor manages script registration and loading.
 type ScriptDescriptor struct {
 	container map[string]string
@@ -78,8 +89,20 @@ func NewScriptDescriptor(ctx context.Context, client *redis.Client, scripts map[
 	return sd, nil
 }
 
-// Register loads scripts into Redis and records their SHA1 hashes.
-func (sd *ScriptDescriptor) Register(ctx context.Context, client *redis.Client, scripts map[string]string, redisScriptDefinition string, db int) error {
+// Register loads scripts into Redis and records their SHA1 hashes, and the
+// time of the call as the last use of the definition (see Prune).
+func (sd *ScriptDescriptor) Register(ctx context.Context, client *redis.Client, scripts map[string]string, redisScriptDefinition string, db int) (err error) {
+	ctx, end := startSpan(ctx, client, "goscriptor.register",
+		redis.Attr{Key: attrScriptCount, Value: len(scripts)},
+		redis.Attr{Key: attrScriptDB, Value: db},
+	)
+	defer func() { end(err) }()
+
+	// Record the use first, so that a concurrent Prune keeps the definition.
+	if err := touchDefinition(ctx, client, redisScriptDefinition, db); err != nil {
+		return err
+	}
+
 	sd.container = make(map[string]string)
 
 	for name, body := range scripts {
@@ -106,51 +129,72 @@ func (sd *ScriptDescriptor) Register(ctx context.Context, client *redis.Client,
 }
 
 // LoadScripts loads previously registered script SHA1 hashes from Redis.
-func (sd *ScriptDescriptor) LoadScripts(ctx context.Context, client *redis.Client, redisScriptDefinition string, db int) error {
+// It also records the time of the call as the last use of the definition,
+// on a best-effort basis: a read-only user or a replica can still load.
+func (sd *ScriptDescriptor) LoadScripts(ctx context.Context, client *redis.Client, redisScriptDefinition string, db int) (err error) {
 	if client == nil {
 		return ErrNilClient
 	}
+	ctx, end := startSpan(ctx, client, "goscriptor.load", redis.Attr{Key: attrScriptDB, Value: db})
+	defer func() { end(err) }()
 
-	res, err := client.Eval(ctx, loadLuaScriptTemplate, []string{redisScriptDefinition}, db)
-	if err != nil {
+	scripts, err := getLuaScripts(ctx, client, redisScriptDefinition, db)
+	if err != nil || len(scripts) == 0 {
 		return err
 	}
 
-	if v, ok := res.([]any); ok {
-		count := len(v)
-		if count == 0 {
-			return nil
+	sd.container = make(map[string]string)
+	for name, sha1 := range scripts {
+		exists, err := client.ScriptExists(ctx, sha1)
+		if err != nil {
+			return err
 		}
-		if count%2 != 0 {
-			return fmt.Errorf("goscriptor: HGETALL returned odd number of elements (%d)", count)
+		if !exists {
+			return ErrScriptNotCached
 		}
+		sd.container[name] = sha1
+	}
+	touchDefinition(ctx, client, redisScriptDefinition, db)
+	return nil
+}
 
-		sd.container = make(map[string]string)
-		for i := 0; i < count; i = i + 2 {
-			key, value := v[i], v[i+1]
-
-			keyStr, ok1 := key.(string)
-			valueStr, ok2 := value.(string)
-			if !ok1 || !ok2 {
-				return fmt.Errorf("goscriptor: unexpected type %T or %T from HGETALL", key, value)
-			}
+// getLuaScripts reads a whole registry hash. A missing key reads as empty.
+func getLuaScripts(ctx context.Context, client *redis.Client, redisScriptDefinition string, db int) (map[string]string, error) {
+	res, err := registryCall(ctx, client, loadLuaScriptTemplate, db, "HGETALL", redisScriptDefinition)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := res.(map[any]any); ok {
+		// RESP3 map from a direct HGETALL in cluster mode
+		flat := make([]any, 0, 2*len(m))
+		for k, v := range m {
+			flat = append(flat, k, v)
+		}
+		res = flat
+	}
 
-			exists, err := client.ScriptExists(ctx, valueStr)
-			if err != nil {
-				return err
-			}
-			if !exists {
-				return ErrScriptNotCached
-			}
-			sd.container[keyStr] = valueStr
+	v, _ := res.([]any)
+	count := len(v)
+	if count%2 != 0 {
+		return nil, fmt.Errorf("goscriptor: HGETALL returned odd number of elements (%d)", count)
+	}
+	scripts := make(map[string]string, count/2)
+	for i := 0; i < count; i = i + 2 {
+		key, value := v[i], v[i+1]
+
+		keyStr, ok1 := key.(string)
+		valueStr, ok2 := value.(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("goscriptor: unexpected type %T or %T from HGETALL", key, value)
 		}
+		scripts[keyStr] = valueStr
 	}
-	return nil
+	return scripts, nil
 }
 
 // keyExistsLuaScript checks if the script definition key exists.
 func keyExistsLuaScript(ctx context.Context, client *redis.Client, redisScriptDefinition string, db int) error {
-	exists, err := client.Eval(ctx, existsLuaScriptTemplate, []string{redisScriptDefinition}, db)
+	exists, err := registryCall(ctx, client, existsLuaScriptTemplate, db, "EXISTS", redisScriptDefinition)
 	if err != nil {
 		return err
 	}
@@ -167,7 +211,7 @@ func keyExistsLuaScript(ctx context.Context, client *redis.Client, redisScriptDe
 
 // mkeyExistsLuaScript checks if a script member key exists in the hash.
 func mkeyExistsLuaScript(ctx context.Context, client *redis.Client, redisScriptDefinition string, mkey string, db int) error {
-	exists, err := client.Eval(ctx, hexistsLuaScriptTemplate, []string{redisScriptDefinition}, db, mkey)
+	exists, err := registryCall(ctx, client, hexistsLuaScriptTemplate, db, "HEXISTS", redisScriptDefinition, mkey)
 	if err != nil {
 		return err
 	}
@@ -184,7 +228,7 @@ func mkeyExistsLuaScript(ctx context.Context, client *redis.Client, redisScriptD
 
 // getLuaScript retrieves a script's SHA1 from the Redis hash.
 func getLuaScript(ctx context.Context, client *redis.Client, redisScriptDefinition string, name string, db int) (string, error) {
-	exists, err := client.Eval(ctx, getLuaScriptTemplate, []string{redisScriptDefinition}, db, name)
+	exists, err := registryCall(ctx, client, getLuaScriptTemplate, db, "HGET", redisScriptDefinition, name)
 	if err != nil {
 		return "", err
 	}
@@ -201,19 +245,31 @@ func getLuaScript(ctx context.Context, client *redis.Client, redisScriptDefiniti
 
 // setLuaScript stores a script's SHA1 in the Redis hash.
 func setLuaScript(ctx context.Context, client *redis.Client, redisScriptDefinition string, name string, sha1 string, db int) error {
-	_, err := client.Eval(ctx, setLuaScriptTemplate, []string{redisScriptDefinition}, db, name, sha1)
+	_, err := registryCall(ctx, client, setLuaScriptTemplate, db, "HSET", redisScriptDefinition, name, sha1)
 	return err
 }
 
 // availableLuaScript checks that a script exists in both the hash and the
 // Redis script cache, using a single EVAL round-trip for the hash lookup.
 func availableLuaScript(ctx context.Context, client *redis.Client, redisScriptDefinition string, db int, name string) (string, error) {
-	res, err := client.Eval(ctx, availableLuaScriptTemplate, []string{redisScriptDefinition}, db, name)
+	var res any
+	var err error
+	if client.IsCluster() {
+		// No SELECT in a cluster: a missing key or field both read as nil.
+		res, err = client.Do(ctx, "HGET", redisScriptDefinition, name)
+		if err == nil && res == nil {
+			return "", ErrKeyNotFound
+		}
+	} else {
+		res, err = client.Eval(ctx, availableLuaScriptTemplate, []string{redisScriptDefinition}, db, name)
+	}
 	if err != nil {
 		// Map Lua error replies to sentinel errors
-		errMsg := err.Error()
-		if errMsg == "KEY_NOT_FOUND" || errMsg == "FIELD_NOT_FOUND" {
-			return "", ErrKeyNotFound
+		var rerr redis.RedisError
+		if errors.As(err, &rerr) {
+			if code := rerr.Code(); code == "KEY_NOT_FOUND" || code == "FIELD_NOT_FOUND" {
+				return "", ErrKeyNotFound
+			}
 		}
 		return "", err
 	}
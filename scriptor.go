@@ -7,11 +7,17 @@
 //
 //	import "github.com/yshengliao/goscriptor/redis"
 //
-// Note: This library uses the SELECT command internally. Redis Cluster is not supported.
+// The registry hash is kept in scriptDB by way of SELECT inside Lua templates.
+// With a client from redis.NewClusterClient, the registry is accessed without
+// SELECT (scriptDB must be 0), scripts are loaded on every master and ExecSha
+// is routed by the slot of its first key.
 package goscriptor
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/yshengliao/goscriptor/redis"
@@ -22,23 +28,33 @@ const scriptDefinition = "scriptor_v.0.0.0"
 // Scriptor manages Redis Lua scripts.
 type Scriptor struct {
 	Client                *redis.Client
-	scripts               map[string]string
+	mu                    sync.RWMutex
+	scripts               map[string]string // name -> SHA1
+	bodies                map[string]string // name -> Lua source, used to recover from NOSCRIPT
+	idempotent            map[string]bool   // names marked with MarkIdempotent
 	redisScriptDB         int
 	redisScriptDefinition string
 }
 
 // New creates a new scriptor with the given redis client.
-// Note: goscriptor does not support Redis Cluster because it uses the SELECT command internally.
+// A cluster client (redis.NewClusterClient) requires scriptDB 0.
 func New(client *redis.Client, scriptDB int, redisScriptDefinition string, scripts map[string]string) (*Scriptor, error) {
 	if client == nil {
 		return nil, ErrNilClient
 	}
+	if client.IsCluster() && scriptDB != 0 {
+		return nil, ErrClusterDB
+	}
 
 	s := &Scriptor{
 		Client:        client,
 		scripts:       make(map[string]string),
+		bodies:        make(map[string]string, len(scripts)),
 		redisScriptDB: scriptDB,
 	}
+	for name, body := range scripts {
+		s.bodies[name] = body
+	}
 
 	if redisScriptDefinition != "" {
 		s.redisScriptDefinition = redisScriptDefinition
@@ -80,14 +96,140 @@ func (s *Scriptor) Exec(ctx context.Context, script string, keys []string, args
 }
 
 // ExecSha executes a cached Lua script by name.
-func (s *Scriptor) ExecSha(ctx context.Context, scriptname string, keys []string, args ...any) (any, error) {
+//
+// If Redis replies with NOSCRIPT (e.g. after a restart or SCRIPT FLUSH) and the
+// script body is known, the script is reloaded, its new SHA1 is recorded in the
+// registry hash, and the call is retried once. If the reload fails, the
+// returned error wraps both its cause and redis.ErrNoScript. Scripts marked
+// with MarkIdempotent are also retried by the client after connection errors.
+func (s *Scriptor) ExecSha(ctx context.Context, scriptname string, keys []string, args ...any) (_ any, err error) {
+	s.mu.RLock()
 	sha, ok := s.scripts[scriptname]
+	idempotent := s.idempotent[scriptname]
+	s.mu.RUnlock()
 	if !ok || sha == "" {
 		return nil, ErrScriptNotFound
 	}
+
+	ctx, end := startSpan(ctx, s.Client, "goscriptor.exec",
+		redis.Attr{Key: attrScriptName, Value: scriptname},
+		redis.Attr{Key: attrScriptSHA, Value: sha},
+		redis.Attr{Key: attrScriptKeys, Value: len(keys)},
+		redis.Attr{Key: attrScriptDB, Value: s.redisScriptDB},
+	)
+	defer func() { end(err) }()
+
+	if idempotent {
+		ctx = redis.WithIdempotent(ctx)
+	}
+	res, err := s.Client.EvalSha(ctx, sha, keys, args...)
+	if err == nil || !errors.Is(err, redis.ErrNoScript) {
+		return res, err
+	}
+
+	sha, rerr := s.reload(ctx, scriptname)
+	if rerr != nil {
+		return nil, fmt.Errorf("goscriptor: reload %q after NOSCRIPT: %w (%w)", scriptname, rerr, err)
+	}
 	return s.Client.EvalSha(ctx, sha, keys, args...)
 }
 
+// MarkIdempotent marks scripts as safe to run twice, e.g. scripts that only
+// set values, so that ExecSha lets the client retry them after a connection
+// error (see redis.Options.MaxRetries). It returns ErrScriptNotFound for an
+// unknown name.
+func (s *Scriptor) MarkIdempotent(names ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range names {
+		if _, ok := s.scripts[name]; !ok {
+			return ErrScriptNotFound
+		}
+	}
+	if s.idempotent == nil {
+		s.idempotent = make(map[string]bool, len(names))
+	}
+	for _, name := range names {
+		s.idempotent[name] = true
+	}
+	return nil
+}
+
+// reload loads a script body into the Redis script cache again and updates
+// both the local SHA1 map and the registry hash.
+func (s *Scriptor) reload(ctx context.Context, scriptname string) (_ string, err error) {
+	s.mu.RLock()
+	body, ok := s.bodies[scriptname]
+	s.mu.RUnlock()
+	if !ok {
+		return "", ErrScriptNotCached
+	}
+
+	ctx, end := startSpan(ctx, s.Client, "goscriptor.reload",
+		redis.Attr{Key: attrScriptName, Value: scriptname},
+		redis.Attr{Key: attrScriptDB, Value: s.redisScriptDB},
+	)
+	defer func() { end(err) }()
+
+	sha, err := s.Client.ScriptLoad(ctx, body)
+	if err != nil {
+		return "", err
+	}
+	if err := setLuaScript(ctx, s.Client, s.redisScriptDefinition, scriptname, sha, s.redisScriptDB); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.scripts[scriptname] = sha
+	s.mu.Unlock()
+	return sha, nil
+}
+
+// Scripts returns a copy of the script name to SHA1 map of the definition.
+func (s *Scriptor) Scripts() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scripts := make(map[string]string, len(s.scripts))
+	for name, sha := range s.scripts {
+		scripts[name] = sha
+	}
+	return scripts
+}
+
+// Definitions lists every script definition registered in the script DB,
+// including older versions of this one, sorted by name.
+func (s *Scriptor) Definitions(ctx context.Context) ([]Definition, error) {
+	return listDefinitions(ctx, s.Client, s.redisScriptDB)
+}
+
+// Diff compares the scripts of two definitions, e.g. "myapp|v1.0" and
+// "myapp|v1.1".
+func (s *Scriptor) Diff(ctx context.Context, from, to string) (ScriptDiff, error) {
+	return diffDefinitions(ctx, s.Client, from, to, s.redisScriptDB)
+}
+
+// DeleteDefinition removes another script definition from the script DB. It
+// returns ErrDefinitionInUse for the definition of s.
+func (s *Scriptor) DeleteDefinition(ctx context.Context, name string) error {
+	if name == s.redisScriptDefinition {
+		return ErrDefinitionInUse
+	}
+	return deleteDefinition(ctx, s.Client, name, s.redisScriptDB)
+}
+
+// Prune deletes the script definitions that were not registered or loaded
+// for longer than unused, e.g. 30*24*time.Hour, and returns their names. The
+// definition of s is never pruned.
+func (s *Scriptor) Prune(ctx context.Context, unused time.Duration) ([]string, error) {
+	return pruneDefinitions(ctx, s.Client, unused, s.redisScriptDB, s.redisScriptDefinition)
+}
+
+// AdoptDefinitions indexes definitions registered by a release without the
+// index, e.g. "myapp|v0.9", so that Definitions and Prune see them.
+func (s *Scriptor) AdoptDefinitions(ctx context.Context, names ...string) error {
+	return adoptDefinitions(ctx, s.Client, s.redisScriptDB, names)
+}
+
 // Close closes the underlying Redis client.
 func (s *Scriptor) Close() error {
 	return s.Client.Close()
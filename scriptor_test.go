@@ -3,9 +3,13 @@ package goscriptor_test
 import (
 	"context"
 	"errors"
+	"net"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/yshengliao/goscriptor"
+	"github.com/yshengliao/goscriptor/redis"
 )
 
 const (
@@ -244,3 +248,182 @@ func TestClose(t *testing.T) {
 		t.Fatalf("Close: %v", err)
 	}
 }
+
+func TestExecSha_NoScriptRecovery(t *testing.T) {
+	_ = redisAddr(t)
+
+	s := newTestDB(t, scripts)
+	ctx := context.Background()
+
+	if _, err := s.Client.Do(ctx, "SCRIPT", "FLUSH"); err != nil {
+		t.Fatalf("SCRIPT FLUSH: %v", err)
+	}
+
+	res, err := s.ExecSha(ctx, hello, []string{""})
+	if err != nil {
+		t.Fatalf("ExecSha after flush: %v", err)
+	}
+	if res.(string) != "Hello, World!" {
+		t.Fatalf("expected 'Hello, World!', got %v", res)
+	}
+
+	// The registry hash must point at a cached script again.
+	s2, err := goscriptor.New(s.Client, 1, scriptDefinition, nil)
+	if err != nil {
+		t.Fatalf("New reload after recovery: %v", err)
+	}
+	assertTestCase(t, s2)
+}
+
+func TestExecSha_NoScriptWithoutBody(t *testing.T) {
+	_ = redisAddr(t)
+
+	_ = newTestDB(t, scripts)
+	s := newTestNew(t, nil)
+	ctx := context.Background()
+
+	if _, err := s.Client.Do(ctx, "SCRIPT", "FLUSH"); err != nil {
+		t.Fatalf("SCRIPT FLUSH: %v", err)
+	}
+
+	_, err := s.ExecSha(ctx, hello, []string{""})
+	if err == nil {
+		t.Fatal("expected NOSCRIPT error when script body is unknown")
+	}
+	if !errors.Is(err, redis.ErrNoScript) || !errors.Is(err, goscriptor.ErrScriptNotCached) {
+		t.Fatalf("expected the reload error wrapped with NOSCRIPT, got %v", err)
+	}
+}
+
+func TestNew_Cluster(t *testing.T) {
+	addr := redisClusterAddr(t)
+	ctx := context.Background()
+
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{addr}})
+	defer client.Close()
+	if err := client.FlushAll(ctx); err != nil {
+		t.Fatalf("FlushAll: %v", err)
+	}
+
+	if _, err := goscriptor.New(client, 1, scriptDefinition, scripts); !errors.Is(err, goscriptor.ErrClusterDB) {
+		t.Fatalf("expected ErrClusterDB, got %v", err)
+	}
+
+	s, err := goscriptor.New(client, 0, scriptDefinition, scripts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	assertTestCase(t, s)
+
+	// Reload the registry written without SELECT.
+	s2, err := goscriptor.New(client, 0, scriptDefinition, nil)
+	if err != nil {
+		t.Fatalf("New reload: %v", err)
+	}
+	assertTestCase(t, s2)
+
+	// NOSCRIPT recovery reloads the script on every master.
+	if _, err := client.Do(ctx, "SCRIPT", "FLUSH"); err != nil {
+		t.Fatalf("SCRIPT FLUSH: %v", err)
+	}
+	res, err := s.ExecSha(ctx, hello, []string{"{user:1}"})
+	if err != nil {
+		t.Fatalf("ExecSha after flush: %v", err)
+	}
+	if res.(string) != "Hello, World!" {
+		t.Fatalf("expected 'Hello, World!', got %v", res)
+	}
+}
+
+// dropConn closes the connection right after the next write, so that the
+// command reaches Redis but its reply is lost.
+type dropConn struct {
+	net.Conn
+	drop *atomic.Bool
+}
+
+func (c dropConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if c.drop.CompareAndSwap(true, false) {
+		c.Conn.Close()
+	}
+	return n, err
+}
+
+func TestExecSha_MarkIdempotent(t *testing.T) {
+	addr := redisAddr(t)
+	ctx := context.Background()
+
+	var drop atomic.Bool
+	client := redis.NewClient(&redis.Options{
+		Addr: addr,
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var d net.Dialer
+			nc, err := d.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return dropConn{Conn: nc, drop: &drop}, nil
+		},
+	})
+	s, err := goscriptor.New(client, 1, scriptDefinition, scripts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	// Not marked: the lost reply is returned as an error.
+	drop.Store(true)
+	if _, err := s.ExecSha(ctx, hello, []string{""}); err == nil {
+		t.Fatal("expected a connection error")
+	}
+
+	if err := s.MarkIdempotent(hello + " not found"); !errors.Is(err, goscriptor.ErrScriptNotFound) {
+		t.Fatalf("expected ErrScriptNotFound, got %v", err)
+	}
+	if err := s.MarkIdempotent(hello); err != nil {
+		t.Fatalf("MarkIdempotent: %v", err)
+	}
+	drop.Store(true)
+	res, err := s.ExecSha(ctx, hello, []string{""})
+	if err != nil {
+		t.Fatalf("ExecSha: %v", err)
+	}
+	if res.(string) != "Hello, World!" {
+		t.Fatalf("expected 'Hello, World!', got %v", res)
+	}
+}
+
+func TestScriptor_Registry(t *testing.T) {
+	old := newTestDB(t, map[string]string{hello: _HelloworldTemplate, "old": `return 0`})
+	old.Close()
+
+	host, port := splitAddr(t, redisAddr(t))
+	s, err := goscriptor.NewDB(&goscriptor.Option{Host: host, Port: port, PoolSize: 1}, 1, "scriptKey|0.0.1", scripts)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	if got := s.Scripts(); len(got) != 1 || got[hello] == "" {
+		t.Fatalf("expected the hello script, got %v", got)
+	}
+	defs, err := s.Definitions(ctx)
+	if err != nil || len(defs) != 2 {
+		t.Fatalf("expected 2 definitions, got %+v, %v", defs, err)
+	}
+	diff, err := s.Diff(ctx, scriptDefinition, "scriptKey|0.0.1")
+	if err != nil || len(diff.Removed) != 1 || diff.Removed[0] != "old" {
+		t.Fatalf("expected old to be removed, got %+v, %v", diff, err)
+	}
+
+	if err := s.DeleteDefinition(ctx, "scriptKey|0.0.1"); !errors.Is(err, goscriptor.ErrDefinitionInUse) {
+		t.Fatalf("expected ErrDefinitionInUse, got %v", err)
+	}
+	// Every definition is older than -1s, but the one in use is kept.
+	pruned, err := s.Prune(ctx, -time.Second)
+	if err != nil || len(pruned) != 1 || pruned[0] != scriptDefinition {
+		t.Fatalf("expected %s to be pruned, got %v, %v", scriptDefinition, pruned, err)
+	}
+}
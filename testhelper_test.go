@@ -18,6 +18,17 @@ func redisAddr(t *testing.T) string {
 	return addr
 }
 
+// redisClusterAddr returns a Redis Cluster node address from the
+// REDIS_CLUSTER_ADDR env var. Tests that need a cluster skip if not set.
+func redisClusterAddr(t *testing.T) string {
+	t.Helper()
+	addr := os.Getenv("REDIS_CLUSTER_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_CLUSTER_ADDR not set, skipping cluster integration test")
+	}
+	return addr
+}
+
 // splitAddr splits "host:port" into (host, port).
 func splitAddr(t *testing.T, addr string) (string, int) {
 	t.Helper()
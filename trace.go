@@ -0,0 +1,33 @@
+package goscriptor
+
+import (
+	"context"
+
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+// Span attribute keys for script operations.
+const (
+	attrScriptName  = "goscriptor.script.name"
+	attrScriptSHA   = "goscriptor.script.sha"
+	attrScriptKeys  = "goscriptor.script.keys"
+	attrScriptDB    = "goscriptor.script.db"
+	attrScriptCount = "goscriptor.script.count"
+)
+
+// startSpan starts a span with the tracer of client (redis.Options.Tracer).
+// The returned function ends it, recording err if non-nil. Without a tracer
+// both are no-ops.
+func startSpan(ctx context.Context, client *redis.Client, name string, attrs ...redis.Attr) (context.Context, func(err error)) {
+	tracer := client.Tracer()
+	if tracer == nil {
+		return ctx, func(error) {}
+	}
+	ctx, span := tracer.Start(ctx, name, attrs...)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,105 @@
+package goscriptor_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/yshengliao/goscriptor"
+	"github.com/yshengliao/goscriptor/redis"
+)
+
+type recTracer struct {
+	mu    sync.Mutex
+	spans []*recSpan
+}
+
+type recSpan struct {
+	name   string
+	attrs  map[string]any
+	parent *recSpan
+	err    error
+}
+
+type recSpanKey struct{}
+
+func (rt *recTracer) Start(ctx context.Context, name string, attrs ...redis.Attr) (context.Context, redis.Span) {
+	s := &recSpan{name: name, attrs: make(map[string]any)}
+	s.parent, _ = ctx.Value(recSpanKey{}).(*recSpan)
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+	rt.mu.Lock()
+	rt.spans = append(rt.spans, s)
+	rt.mu.Unlock()
+	return context.WithValue(ctx, recSpanKey{}, s), s
+}
+
+func (s *recSpan) RecordError(err error) { s.err = err }
+func (s *recSpan) End()                  {}
+
+// find returns the first span named name and forgets every span.
+func (rt *recTracer) find(name string) *recSpan {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	spans := rt.spans
+	rt.spans = nil
+	for _, s := range spans {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestScriptor_Tracing(t *testing.T) {
+	addr := redisAddr(t)
+	ctx := context.Background()
+
+	rt := &recTracer{}
+	client := redis.NewClient(&redis.Options{Addr: addr, PoolSize: 1, Tracer: rt})
+	defer client.Close()
+	if err := client.FlushAll(ctx); err != nil {
+		t.Fatalf("FlushAll: %v", err)
+	}
+
+	s, err := goscriptor.New(client, 1, scriptDefinition, scripts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	reg := rt.find("goscriptor.register")
+	if reg == nil || reg.attrs["goscriptor.script.count"] != 1 || reg.attrs["goscriptor.script.db"] != 1 {
+		t.Fatalf("unexpected register span %+v", reg)
+	}
+
+	if _, err := s.ExecSha(ctx, hello, []string{"k"}); err != nil {
+		t.Fatalf("ExecSha: %v", err)
+	}
+	rt.mu.Lock()
+	spans := rt.spans
+	rt.mu.Unlock()
+	if len(spans) != 2 {
+		t.Fatalf("expected exec and EVALSHA spans, got %d", len(spans))
+	}
+	exec, evalsha := spans[0], spans[1]
+	if exec.name != "goscriptor.exec" || exec.attrs["goscriptor.script.name"] != hello ||
+		exec.attrs["goscriptor.script.sha"] == "" || exec.attrs["goscriptor.script.keys"] != 1 {
+		t.Fatalf("unexpected exec span %+v", exec)
+	}
+	if evalsha.name != "EVALSHA" || evalsha.parent != exec {
+		t.Fatalf("expected EVALSHA child span, got %+v", evalsha)
+	}
+	rt.find("")
+
+	// NOSCRIPT recovery shows up as a reload under the exec span.
+	if _, err := client.Do(ctx, "SCRIPT", "FLUSH"); err != nil {
+		t.Fatalf("SCRIPT FLUSH: %v", err)
+	}
+	rt.find("")
+	if _, err := s.ExecSha(ctx, hello, []string{"k"}); err != nil {
+		t.Fatalf("ExecSha after flush: %v", err)
+	}
+	if reload := rt.find("goscriptor.reload"); reload == nil || reload.parent == nil || reload.parent.name != "goscriptor.exec" {
+		t.Fatalf("unexpected reload span %+v", reload)
+	}
+}